@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.eigsys.de/masquerade/pkg/cache"
+	"go.eigsys.de/masquerade/pkg/importpath"
+	"go.eigsys.de/masquerade/pkg/repository"
+	"log"
+	"net/http"
+	"strings"
+)
+
+const (
+	adminActionLabel = "action"
+
+	adminActionList         = "list"
+	adminActionPurge        = "purge"
+	adminActionPurgeAll     = "purge_all"
+	adminActionRefresh      = "refresh"
+	adminActionUnauthorized = "unauthorized"
+)
+
+type CacheAdmin interface {
+	Entries() ([]cache.EntryInfo, error)
+	Invalidate(module string) (int, error)
+	Clear() error
+}
+
+type adminResult struct {
+	Module string `json:"module,omitempty"`
+	Purged int    `json:"purged"`
+}
+
+func (a *AppContext) ListenAndServeAdmin() error {
+	if a.AdminAddr == "" {
+		return nil
+	}
+
+	a.adminServer = newServer(a.AdminAddr, a.getAdminMux())
+
+	log.Printf("serving admin API on %q", a.adminServer.Addr)
+
+	return a.adminServer.ListenAndServe()
+}
+
+func (a *AppContext) getAdminMux() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /cache", a.handleAdminAuthorization(a.handleAdminList))
+	mux.HandleFunc("DELETE /cache", a.handleAdminAuthorization(a.handleAdminPurgeAll))
+	mux.HandleFunc("DELETE /cache/{module}", a.handleAdminAuthorization(a.handleAdminPurge))
+	mux.HandleFunc("POST /cache/{module}/refresh", a.handleAdminAuthorization(a.handleAdminRefresh))
+
+	return mux
+}
+
+func (a *AppContext) countAdminAction(action string) {
+	a.Metrics.AdminActions.With(prometheus.Labels{adminActionLabel: action}).Inc()
+}
+
+func (a *AppContext) handleAdminAuthorization(handler http.HandlerFunc) http.HandlerFunc {
+	return func(response http.ResponseWriter, request *http.Request) {
+		token, ok := strings.CutPrefix(request.Header.Get("Authorization"), "Bearer ")
+		if !ok || a.AdminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(a.AdminToken)) != 1 {
+			log.Printf("admin: unauthorized %s %s from %s", request.Method, request.URL.Path, request.RemoteAddr)
+			a.countAdminAction(adminActionUnauthorized)
+
+			response.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(response, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if _, ok := a.current().Cache.(CacheAdmin); !ok {
+			http.Error(response, "not implemented", http.StatusNotImplemented)
+			return
+		}
+
+		handler(response, request)
+	}
+}
+
+func (a *AppContext) handleAdminList(response http.ResponseWriter, _ *http.Request) {
+	entries, err := a.current().Cache.(CacheAdmin).Entries()
+	if err != nil {
+		log.Printf("admin: listing cache entries: %s", err)
+		http.Error(response, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("admin: listed %d cache entries", len(entries))
+	a.countAdminAction(adminActionList)
+
+	writeJSON(response, entries)
+}
+
+func (a *AppContext) handleAdminPurgeAll(response http.ResponseWriter, _ *http.Request) {
+	if err := a.current().Cache.(CacheAdmin).Clear(); err != nil {
+		log.Printf("admin: purging the cache: %s", err)
+		http.Error(response, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Print("admin: purged the cache")
+	a.countAdminAction(adminActionPurgeAll)
+
+	response.WriteHeader(http.StatusNoContent)
+}
+
+func (a *AppContext) handleAdminPurge(response http.ResponseWriter, request *http.Request) {
+	module, ok := parseAdminModule(request)
+	if !ok {
+		http.Error(response, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	purged, err := a.current().Cache.(CacheAdmin).Invalidate(module)
+	if err != nil {
+		log.Printf("admin: purging %q: %s", module, err)
+		http.Error(response, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("admin: purged %d cache entries of %q", purged, module)
+	a.countAdminAction(adminActionPurge)
+
+	writeJSON(response, &adminResult{Module: module, Purged: purged})
+}
+
+func (a *AppContext) handleAdminRefresh(response http.ResponseWriter, request *http.Request) {
+	module, ok := parseAdminModule(request)
+	if !ok {
+		http.Error(response, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	// The repository is fetched with the backend whose cache entries are purged, even if the settings are reloaded.
+	current := a.current()
+
+	purged, err := current.Cache.(CacheAdmin).Invalidate(module)
+	if err != nil {
+		log.Printf("admin: refreshing %q: %s", module, err)
+		http.Error(response, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("admin: refreshing %q, purged %d cache entries", module, purged)
+	a.countAdminAction(adminActionRefresh)
+
+	_, err, _ = current.Cache.Memoize(module, cache.Detach(request.Context(), backendTimeout, a.resilient(func(ctx context.Context) (any, error) {
+		return current.VCSHandler.Fetch(ctx, module)
+	})))
+	if err != nil {
+		log.Printf("admin: refreshing %q: %s", module, err)
+
+		if errors.Is(err, repository.ErrNotFound) {
+			http.Error(response, "module not found", http.StatusNotFound)
+			return
+		}
+
+		http.Error(response, "bad gateway", http.StatusBadGateway)
+		return
+	}
+
+	writeJSON(response, &adminResult{Module: module, Purged: purged})
+}
+
+func parseAdminModule(request *http.Request) (string, bool) {
+	module := request.PathValue("module")
+
+	importPath, err := importpath.Parse(module)
+	if err != nil || importPath.Repo != module || importPath.Major != "" || importPath.Subpath != "" {
+		return "", false
+	}
+
+	return module, true
+}
+
+func writeJSON(response http.ResponseWriter, v any) {
+	response.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(response).Encode(v); err != nil {
+		log.Print(err)
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.eigsys.de/masquerade/pkg/cache"
+	"go.eigsys.de/masquerade/pkg/repository"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newAdminTestAppContext(vcsHandler VCSHandler) *AppContext {
+	appContext := &AppContext{
+		Metrics:    NewMetrics(false, &mockRegistry{}, &mockRegistry{}),
+		VCSHandler: vcsHandler,
+		Cache:      cache.NewMemoizer(cache.NewMemoryStore(0), time.Hour, time.Minute, 0),
+		AdminToken: "the-token",
+	}
+
+	_, _, _ = appContext.Cache.Memoize("foo", func() (any, error) { return &mockRepository{}, nil })
+	_, _, _ = appContext.Cache.Memoize(cache.Key("foo", "modules"), func() (any, error) { return []string{}, nil })
+	_, _, _ = appContext.Cache.Memoize("bar", func() (any, error) { return &mockRepository{}, nil })
+
+	return appContext
+}
+
+func Test_appContext_getAdminMux(t *testing.T) {
+	tests := []struct {
+		name       string
+		vcsHandler VCSHandler
+		method     string
+		path       string
+		token      string
+		wantCode   int
+		wantBody   string
+		wantKeys   []string
+		wantAction string
+	}{
+		{
+			name:       "unauthorized",
+			method:     http.MethodGet,
+			path:       "/cache",
+			wantCode:   http.StatusUnauthorized,
+			wantBody:   "unauthorized\n",
+			wantKeys:   []string{"bar", "foo", "foo#modules"},
+			wantAction: adminActionUnauthorized,
+		},
+		{
+			name:       "wrong-token",
+			method:     http.MethodDelete,
+			path:       "/cache",
+			token:      "wrong-token",
+			wantCode:   http.StatusUnauthorized,
+			wantBody:   "unauthorized\n",
+			wantKeys:   []string{"bar", "foo", "foo#modules"},
+			wantAction: adminActionUnauthorized,
+		},
+		{
+			name:       "list",
+			method:     http.MethodGet,
+			path:       "/cache",
+			token:      "the-token",
+			wantCode:   http.StatusOK,
+			wantBody:   `"key":"foo#modules"`,
+			wantKeys:   []string{"bar", "foo", "foo#modules"},
+			wantAction: adminActionList,
+		},
+		{
+			name:       "purge",
+			method:     http.MethodDelete,
+			path:       "/cache/foo",
+			token:      "the-token",
+			wantCode:   http.StatusOK,
+			wantBody:   `{"module":"foo","purged":2}`,
+			wantKeys:   []string{"bar"},
+			wantAction: adminActionPurge,
+		},
+		{
+			name:     "purge-invalid-module",
+			method:   http.MethodDelete,
+			path:     "/cache/.foo",
+			token:    "the-token",
+			wantCode: http.StatusBadRequest,
+			wantBody: "bad request\n",
+			wantKeys: []string{"bar", "foo", "foo#modules"},
+		},
+		{
+			name:       "purge-all",
+			method:     http.MethodDelete,
+			path:       "/cache",
+			token:      "the-token",
+			wantCode:   http.StatusNoContent,
+			wantKeys:   []string{},
+			wantAction: adminActionPurgeAll,
+		},
+		{
+			name:       "refresh",
+			vcsHandler: &mockVCSHandler{fetchResult: &mockRepository{}},
+			method:     http.MethodPost,
+			path:       "/cache/foo/refresh",
+			token:      "the-token",
+			wantCode:   http.StatusOK,
+			wantBody:   `{"module":"foo","purged":2}`,
+			wantKeys:   []string{"bar", "foo"},
+			wantAction: adminActionRefresh,
+		},
+		{
+			name:       "refresh-not-found",
+			vcsHandler: &mockVCSHandler{fetchErr: repository.ErrNotFound},
+			method:     http.MethodPost,
+			path:       "/cache/foo/refresh",
+			token:      "the-token",
+			wantCode:   http.StatusNotFound,
+			wantBody:   "module not found\n",
+			wantKeys:   []string{"bar", "foo"},
+			wantAction: adminActionRefresh,
+		},
+		{
+			name:       "refresh-error",
+			vcsHandler: &mockVCSHandler{fetchErr: errors.New("error")},
+			method:     http.MethodPost,
+			path:       "/cache/foo/refresh",
+			token:      "the-token",
+			wantCode:   http.StatusBadGateway,
+			wantBody:   "bad gateway\n",
+			wantKeys:   []string{"bar"},
+			wantAction: adminActionRefresh,
+		},
+		{
+			name:     "unknown-endpoint",
+			method:   http.MethodGet,
+			path:     "/foo",
+			token:    "the-token",
+			wantCode: http.StatusNotFound,
+			wantBody: "404 page not found\n",
+			wantKeys: []string{"bar", "foo", "foo#modules"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appContext := newAdminTestAppContext(tt.vcsHandler)
+			request := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				request.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			response := httptest.NewRecorder()
+
+			appContext.getAdminMux().ServeHTTP(response, request)
+
+			if response.Code != tt.wantCode {
+				t.Errorf("code = %v, want %v", response.Code, tt.wantCode)
+			}
+			if !strings.Contains(response.Body.String(), tt.wantBody) {
+				t.Errorf("body = %q, want %q", response.Body.String(), tt.wantBody)
+			}
+
+			entries, _ := appContext.Cache.(CacheAdmin).Entries()
+			keys := []string{}
+			for _, entry := range entries {
+				keys = append(keys, entry.Key)
+			}
+			if strings.Join(keys, ",") != strings.Join(tt.wantKeys, ",") {
+				t.Errorf("keys = %v, want %v", keys, tt.wantKeys)
+			}
+
+			if tt.wantAction != "" {
+				if got := testutil.ToFloat64(appContext.Metrics.AdminActions.With(prometheus.Labels{adminActionLabel: tt.wantAction})); got != 1 {
+					t.Errorf("admin actions = %v, want 1", got)
+				}
+			}
+		})
+	}
+}
+
+func Test_appContext_getAdminMux_unsupportedCache(t *testing.T) {
+	appContext := &AppContext{
+		Metrics:    NewMetrics(false, &mockRegistry{}, &mockRegistry{}),
+		Cache:      &mockMemoizer{},
+		AdminToken: "the-token",
+	}
+	request := httptest.NewRequest(http.MethodGet, "/cache", nil)
+	request.Header.Set("Authorization", "Bearer the-token")
+	response := httptest.NewRecorder()
+
+	appContext.getAdminMux().ServeHTTP(response, request)
+
+	if response.Code != http.StatusNotImplemented {
+		t.Errorf("code = %v", response.Code)
+	}
+}
+
+func Test_appContext_ListenAndServeAdmin_disabled(t *testing.T) {
+	if err := (&AppContext{}).ListenAndServeAdmin(); err != nil {
+		t.Errorf("ListenAndServeAdmin() error = %v", err)
+	}
+}
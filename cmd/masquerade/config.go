@@ -0,0 +1,523 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"go.eigsys.de/masquerade/pkg/gitlab"
+	"gopkg.in/yaml.v3"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envPrefix prefixes the environment variables overriding the configuration, e.g. MASQUERADE_CACHE_TTL.
+const envPrefix = "MASQUERADE"
+
+type Config struct {
+	Listener   ListenerConfig   `yaml:"listener"`
+	Metrics    MetricsConfig    `yaml:"metrics"`
+	Admin      AdminConfig      `yaml:"admin"`
+	Cache      CacheConfig      `yaml:"cache"`
+	Resilience ResilienceConfig `yaml:"resilience"`
+	Backend    BackendConfig    `yaml:"backend"`
+	Templates  TemplatesConfig  `yaml:"templates"`
+}
+
+type ListenerConfig struct {
+	Addr        string `yaml:"addr"`
+	PackageHost string `yaml:"package_host"`
+	HomePageURL string `yaml:"home_page_url"`
+	EnableProxy bool   `yaml:"enable_proxy"`
+}
+
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Addr    string `yaml:"addr"`
+}
+
+type AdminConfig struct {
+	Addr      string `yaml:"addr"`
+	TokenFile string `yaml:"token_file"`
+}
+
+type CacheConfig struct {
+	Backend         string        `yaml:"backend"`
+	TTL             time.Duration `yaml:"ttl"`
+	NegativeTTL     time.Duration `yaml:"negative_ttl"`
+	MaxStale        time.Duration `yaml:"max_stale"`
+	MaxEntries      int           `yaml:"max_entries"`
+	MaxBytes        int64         `yaml:"max_bytes"`
+	Path            string        `yaml:"path"`
+	Redis           RedisConfig   `yaml:"redis"`
+	Prewarm         bool          `yaml:"prewarm"`
+	PrewarmInterval time.Duration `yaml:"prewarm_interval"`
+}
+
+type RedisConfig struct {
+	URL       string        `yaml:"url" secret:"true"`
+	KeyPrefix string        `yaml:"key_prefix"`
+	LocalTTL  time.Duration `yaml:"local_ttl"`
+}
+
+type ResilienceConfig struct {
+	RetryAttempts    int           `yaml:"retry_attempts"`
+	RetryBaseDelay   time.Duration `yaml:"retry_base_delay"`
+	RetryMaxDelay    time.Duration `yaml:"retry_max_delay"`
+	BreakerThreshold int           `yaml:"breaker_threshold"`
+	BreakerCooldown  time.Duration `yaml:"breaker_cooldown"`
+}
+
+// BackendConfig configures the single VCS backend of an instance. Several backends or owners, e.g. per path prefix,
+// aren't supported, because requests, cache keys and the proxy assume one owner.
+type BackendConfig struct {
+	Type             string       `yaml:"type"`
+	ValidateSubpaths bool         `yaml:"validate_subpaths"`
+	ModulePathCheck  string       `yaml:"module_path_check"`
+	GitHub           GitHubConfig `yaml:"github"`
+	GitLab           GitLabConfig `yaml:"gitlab"`
+	Gitea            GiteaConfig  `yaml:"gitea"`
+}
+
+type GitHubConfig struct {
+	Owner             string  `yaml:"owner"`
+	BaseURL           string  `yaml:"base_url"`
+	UploadURL         string  `yaml:"upload_url"`
+	Token             string  `yaml:"token" secret:"true"`
+	TokenFile         string  `yaml:"token_file"`
+	AppID             int64   `yaml:"app_id"`
+	AppInstallationID int64   `yaml:"app_installation_id"`
+	AppPrivateKeyFile string  `yaml:"app_private_key_file"`
+	WebhookSecretFile string  `yaml:"webhook_secret_file"`
+	RequestRate       float64 `yaml:"request_rate"`
+	BucketSize        int     `yaml:"bucket_size"`
+}
+
+type GitLabConfig struct {
+	BaseURL     string  `yaml:"base_url"`
+	Group       string  `yaml:"group"`
+	Token       string  `yaml:"token" secret:"true"`
+	RequestRate float64 `yaml:"request_rate"`
+	BucketSize  int     `yaml:"bucket_size"`
+}
+
+type GiteaConfig struct {
+	BaseURL     string  `yaml:"base_url"`
+	Owner       string  `yaml:"owner"`
+	Token       string  `yaml:"token" secret:"true"`
+	RequestRate float64 `yaml:"request_rate"`
+	BucketSize  int     `yaml:"bucket_size"`
+}
+
+type TemplatesConfig struct {
+	// GoGet is a file containing an HTML template for go-get responses (see goget.TemplateData).
+	GoGet string `yaml:"go_get"`
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		Listener: ListenerConfig{Addr: ":8493"},
+		Metrics:  MetricsConfig{Addr: ":9091"},
+		Cache: CacheConfig{
+			Backend:     "memory",
+			TTL:         1 * time.Hour,
+			NegativeTTL: 1 * time.Minute,
+			MaxEntries:  100000,
+			MaxBytes:    64 << 20,
+			Path:        "masquerade.db",
+			Redis:       RedisConfig{KeyPrefix: "masquerade:", LocalTTL: 10 * time.Second},
+		},
+		Resilience: ResilienceConfig{
+			RetryAttempts:    3,
+			RetryBaseDelay:   100 * time.Millisecond,
+			RetryMaxDelay:    2 * time.Second,
+			BreakerThreshold: 5,
+			BreakerCooldown:  30 * time.Second,
+		},
+		Backend: BackendConfig{
+			Type:            "github",
+			ModulePathCheck: modulePathCheckOff,
+			GitHub:          GitHubConfig{RequestRate: 25, BucketSize: 100},
+			GitLab:          GitLabConfig{BaseURL: gitlab.DefaultBaseURL, RequestRate: 25, BucketSize: 100},
+			Gitea:           GiteaConfig{RequestRate: 25, BucketSize: 100},
+		},
+	}
+}
+
+// loadConfig merges the defaults, the configuration file given by -config, the MASQUERADE_* environment variables
+// and the remaining flags, each overriding the previous ones.
+func loadConfig(args []string, lookupEnv func(key string) (string, bool), output io.Writer) (*Config, error) {
+	var path string
+
+	// The first pass only looks for the configuration file, so that the flags can be applied on top of it.
+	if err := newFlagSet(defaultConfig(), &path, output).Parse(args); err != nil {
+		return nil, err
+	}
+
+	config := defaultConfig()
+
+	if path != "" {
+		if err := config.readFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := applyEnv(reflect.ValueOf(config).Elem(), envPrefix, lookupEnv); err != nil {
+		return nil, err
+	}
+
+	if err := newFlagSet(config, &path, io.Discard).Parse(args); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	return config, nil
+}
+
+func (c *Config) readFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+
+	// An empty file is a valid configuration.
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nil
+}
+
+// applyEnv overrides the fields of v with environment variables named after their YAML keys,
+// e.g. MASQUERADE_BACKEND_GITHUB_OWNER for backend.github.owner.
+func applyEnv(v reflect.Value, key string, lookupEnv func(key string) (string, bool)) error {
+	if v.Kind() == reflect.Struct {
+		for i := range v.NumField() {
+			name := v.Type().Field(i).Tag.Get("yaml")
+			if err := applyEnv(v.Field(i), key+"_"+strings.ToUpper(name), lookupEnv); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	value, ok := lookupEnv(key)
+	if !ok {
+		return nil
+	}
+
+	if err := setValue(v, value); err != nil {
+		return fmt.Errorf("environment variable %s: %w", key, err)
+	}
+
+	return nil
+}
+
+func setValue(v reflect.Value, value string) error {
+	if v.Type() == reflect.TypeFor[time.Duration]() {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(int64(d))
+
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(i)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+// Validate reports all invalid settings at once, named by their keys in the configuration file.
+func (c *Config) Validate() error {
+	var errs []error
+
+	invalid := func(key, message string) {
+		errs = append(errs, fmt.Errorf("%s %s", key, message))
+	}
+
+	oneOf := func(key, value string, values ...string) {
+		if !slices.Contains(values, value) {
+			invalid(key, fmt.Sprintf("must be one of %q, got %q", values, value))
+		}
+	}
+
+	if c.Listener.Addr == "" {
+		invalid("listener.addr", "is required")
+	}
+
+	if c.Listener.PackageHost == "" {
+		invalid("listener.package_host", "is required")
+	}
+
+	if c.Listener.HomePageURL != "" {
+		if u, err := url.Parse(c.Listener.HomePageURL); err != nil || !u.IsAbs() {
+			invalid("listener.home_page_url", "must be an absolute URL")
+		}
+	}
+
+	if c.Metrics.Enabled && c.Metrics.Addr == "" {
+		invalid("metrics.addr", "is required if metrics are enabled")
+	}
+
+	if c.Admin.Addr != "" && c.Admin.TokenFile == "" {
+		invalid("admin.token_file", "is required if the admin API is enabled")
+	}
+
+	oneOf("cache.backend", c.Cache.Backend, "memory", "bolt", "redis")
+
+	if c.Cache.Backend == "bolt" && c.Cache.Path == "" {
+		invalid("cache.path", "is required for the bolt cache backend")
+	}
+
+	if c.Cache.TTL <= 0 {
+		invalid("cache.ttl", "must be positive")
+	}
+
+	for _, setting := range []struct {
+		key   string
+		value int64
+	}{
+		{"cache.negative_ttl", int64(c.Cache.NegativeTTL)},
+		{"cache.max_stale", int64(c.Cache.MaxStale)},
+		{"cache.max_entries", int64(c.Cache.MaxEntries)},
+		{"cache.max_bytes", c.Cache.MaxBytes},
+		{"cache.redis.local_ttl", int64(c.Cache.Redis.LocalTTL)},
+		{"cache.prewarm_interval", int64(c.Cache.PrewarmInterval)},
+		{"resilience.retry_attempts", int64(c.Resilience.RetryAttempts)},
+		{"resilience.retry_base_delay", int64(c.Resilience.RetryBaseDelay)},
+		{"resilience.retry_max_delay", int64(c.Resilience.RetryMaxDelay)},
+		{"resilience.breaker_threshold", int64(c.Resilience.BreakerThreshold)},
+		{"resilience.breaker_cooldown", int64(c.Resilience.BreakerCooldown)},
+	} {
+		if setting.value < 0 {
+			invalid(setting.key, "must not be negative")
+		}
+	}
+
+	oneOf("backend.type", c.Backend.Type, "github", "gitlab", "gitea")
+	oneOf("backend.module_path_check", c.Backend.ModulePathCheck, modulePathCheckOff, modulePathCheckLog, modulePathCheckWarn, modulePathCheckRefuse)
+
+	switch c.Backend.Type {
+	case "github":
+		if c.Backend.GitHub.Owner == "" {
+			invalid("backend.github.owner", "is required")
+		}
+
+		if c.Backend.GitHub.AppID != 0 && (c.Backend.GitHub.AppInstallationID == 0 || c.Backend.GitHub.AppPrivateKeyFile == "") {
+			invalid("backend.github.app_id", "requires backend.github.app_installation_id and backend.github.app_private_key_file")
+		}
+
+		if c.Backend.GitHub.RequestRate <= 0 || c.Backend.GitHub.BucketSize <= 0 {
+			invalid("backend.github.request_rate", "and backend.github.bucket_size must be positive")
+		}
+	case "gitlab":
+		if c.Backend.GitLab.BaseURL == "" {
+			invalid("backend.gitlab.base_url", "is required")
+		}
+
+		if c.Backend.GitLab.Group == "" {
+			invalid("backend.gitlab.group", "is required")
+		}
+
+		if c.Backend.GitLab.RequestRate <= 0 || c.Backend.GitLab.BucketSize <= 0 {
+			invalid("backend.gitlab.request_rate", "and backend.gitlab.bucket_size must be positive")
+		}
+	case "gitea":
+		if c.Backend.Gitea.BaseURL == "" {
+			invalid("backend.gitea.base_url", "is required")
+		}
+
+		if c.Backend.Gitea.Owner == "" {
+			invalid("backend.gitea.owner", "is required")
+		}
+
+		if c.Backend.Gitea.RequestRate <= 0 || c.Backend.Gitea.BucketSize <= 0 {
+			invalid("backend.gitea.request_rate", "and backend.gitea.bucket_size must be positive")
+		}
+	}
+
+	if c.Backend.Type != "github" && c.Backend.GitHub.WebhookSecretFile != "" {
+		invalid("backend.github.webhook_secret_file", "requires the github backend")
+	}
+
+	return errors.Join(errs...)
+}
+
+// withReloadable returns a copy of c with the settings of next that can be changed without a restart: the home
+// page, the cache TTLs, the VCS backend (except for the webhook secret) and the templates.
+func (c *Config) withReloadable(next *Config) *Config {
+	merged := *c
+	merged.Listener.HomePageURL = next.Listener.HomePageURL
+	merged.Cache.TTL = next.Cache.TTL
+	merged.Cache.NegativeTTL = next.Cache.NegativeTTL
+	merged.Cache.MaxStale = next.Cache.MaxStale
+	merged.Backend = next.Backend
+	merged.Backend.GitHub.WebhookSecretFile = c.Backend.GitHub.WebhookSecretFile
+	merged.Templates = next.Templates
+
+	return &merged
+}
+
+// source identifies the repositories served by the backend, whose cache entries are invalid for any other source.
+func (c *BackendConfig) source() string {
+	switch c.Type {
+	case "github":
+		return strings.Join([]string{c.Type, c.GitHub.BaseURL, c.GitHub.Owner}, " ")
+	case "gitlab":
+		return strings.Join([]string{c.Type, c.GitLab.BaseURL, c.GitLab.Group}, " ")
+	case "gitea":
+		return strings.Join([]string{c.Type, c.Gitea.BaseURL, c.Gitea.Owner}, " ")
+	default:
+		return c.Type
+	}
+}
+
+type configChange struct {
+	key         string
+	description string
+}
+
+func (c configChange) String() string {
+	return c.key + ": " + c.description
+}
+
+// diffConfig lists the settings that differ between old and new by their keys, without revealing secrets.
+func diffConfig(old, new *Config) []configChange {
+	var changes []configChange
+
+	diffValues(reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem(), "", false, &changes)
+
+	return changes
+}
+
+func diffValues(old, new reflect.Value, key string, secret bool, changes *[]configChange) {
+	if old.Kind() == reflect.Struct {
+		for i := range old.NumField() {
+			field := old.Type().Field(i)
+			fieldKey := strings.TrimPrefix(key+"."+field.Tag.Get("yaml"), ".")
+
+			diffValues(old.Field(i), new.Field(i), fieldKey, field.Tag.Get("secret") == "true", changes)
+		}
+
+		return
+	}
+
+	if old.Equal(new) {
+		return
+	}
+
+	description := "changed"
+	if !secret {
+		description = formatValue(old) + " -> " + formatValue(new)
+	}
+
+	*changes = append(*changes, configChange{key: key, description: description})
+}
+
+func formatValue(v reflect.Value) string {
+	if v.Kind() == reflect.String {
+		return strconv.Quote(v.String())
+	}
+
+	return fmt.Sprint(v.Interface())
+}
+
+func newFlagSet(config *Config, path *string, output io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet("masquerade", flag.ContinueOnError)
+	flags.SetOutput(output)
+
+	flags.StringVar(path, "config", "", "Configuration file (YAML), whose settings are overridden by MASQUERADE_* environment variables and flags")
+	flags.StringVar(&config.Listener.Addr, "serverAddr", config.Listener.Addr, "HTTP listener address")
+	flags.StringVar(&config.Listener.PackageHost, "packageHost", config.Listener.PackageHost, "Package host")
+	flags.StringVar(&config.Listener.HomePageURL, "homePageURL", config.Listener.HomePageURL, "Home page URL (requesting \"/\") redirects to this URL")
+	flags.BoolVar(&config.Listener.EnableProxy, "enableProxy", config.Listener.EnableProxy, "Serve the GOPROXY protocol for modules below the package host (GitHub only)")
+	flags.BoolVar(&config.Metrics.Enabled, "enableMetrics", config.Metrics.Enabled, "Enable Prometheus metrics on \"/metrics\" of the metrics listener")
+	flags.StringVar(&config.Metrics.Addr, "metricsAddr", config.Metrics.Addr, "Metrics listener address")
+	flags.StringVar(&config.Admin.Addr, "adminAddr", config.Admin.Addr, "Admin API listener address (e.g. \"127.0.0.1:9092\", disabled by default)")
+	flags.StringVar(&config.Admin.TokenFile, "adminTokenFile", config.Admin.TokenFile, "File containing the bearer token for the admin API")
+	flags.StringVar(&config.Cache.Backend, "cacheBackend", config.Cache.Backend, "Cache backend (\"memory\", \"bolt\" or \"redis\")")
+	flags.DurationVar(&config.Cache.TTL, "ttl", config.Cache.TTL, "Cache TTL")
+	flags.DurationVar(&config.Cache.NegativeTTL, "negativeTTL", config.Cache.NegativeTTL, "Cache TTL for repositories that weren't found (0 disables caching them)")
+	flags.DurationVar(&config.Cache.MaxStale, "maxStale", config.Cache.MaxStale, "Serve expired cache entries up to this long while refreshing them in the background, or while the VCS backend fails (0 disables it)")
+	flags.IntVar(&config.Cache.MaxEntries, "cacheMaxEntries", config.Cache.MaxEntries, "Max. number of cache entries, evicting the least recently used ones (memory only, 0 means unlimited)")
+	flags.Int64Var(&config.Cache.MaxBytes, "cacheMaxBytes", config.Cache.MaxBytes, "Max. approximate size of the cache in bytes, evicting the least recently used entries (memory only, 0 means unlimited)")
+	flags.StringVar(&config.Cache.Path, "cachePath", config.Cache.Path, "Cache database file (bolt only)")
+	flags.StringVar(&config.Cache.Redis.URL, "redisURL", config.Cache.Redis.URL, "Redis server URL, which may contain a password (redis only, default: the REDIS_URL environment variable or \"redis://localhost:6379/0\")")
+	flags.StringVar(&config.Cache.Redis.KeyPrefix, "redisKeyPrefix", config.Cache.Redis.KeyPrefix, "Prefix of all Redis keys (redis only)")
+	flags.DurationVar(&config.Cache.Redis.LocalTTL, "redisLocalTTL", config.Cache.Redis.LocalTTL, "Duration for which Redis entries are kept in memory, which delays invalidations by other replicas (redis only, 0 disables it)")
+	flags.BoolVar(&config.Cache.Prewarm, "prewarm", config.Cache.Prewarm, "Fill the cache with all repositories of the owner on startup (GitHub only)")
+	flags.DurationVar(&config.Cache.PrewarmInterval, "prewarmInterval", config.Cache.PrewarmInterval, "Repeat prewarming in this interval (0 prewarms on startup only)")
+	flags.IntVar(&config.Resilience.RetryAttempts, "retryAttempts", config.Resilience.RetryAttempts, "Max. number of attempts to fetch a repository if the VCS backend fails transiently, all within the 30s backend timeout of the fetch (not of the client's request)")
+	flags.DurationVar(&config.Resilience.RetryBaseDelay, "retryBaseDelay", config.Resilience.RetryBaseDelay, "Initial backoff between attempts, which doubles with each attempt and is jittered")
+	flags.DurationVar(&config.Resilience.RetryMaxDelay, "retryMaxDelay", config.Resilience.RetryMaxDelay, "Max. backoff between attempts")
+	flags.IntVar(&config.Resilience.BreakerThreshold, "breakerThreshold", config.Resilience.BreakerThreshold, "Number of consecutive failures of the VCS backend that open the circuit breaker (0 disables it)")
+	flags.DurationVar(&config.Resilience.BreakerCooldown, "breakerCooldown", config.Resilience.BreakerCooldown, "Duration for which the open circuit breaker refuses requests to the VCS backend")
+	flags.StringVar(&config.Backend.Type, "vcsBackend", config.Backend.Type, "VCS backend (\"github\", \"gitlab\" or \"gitea\")")
+	flags.BoolVar(&config.Backend.ValidateSubpaths, "validateSubpaths", config.Backend.ValidateSubpaths, "Verify that the directory of a requested sub-package exists in the repository")
+	flags.StringVar(&config.Backend.ModulePathCheck, "modulePathCheck", config.Backend.ModulePathCheck, "Compare the module path declared in go.mod with the import prefix (\"off\", \"log\", \"warn\" or \"refuse\", GitHub only)")
+	flags.StringVar(&config.Backend.GitHub.Owner, "githubOwner", config.Backend.GitHub.Owner, "GitHub owner")
+	flags.StringVar(&config.Backend.GitHub.BaseURL, "githubBaseURL", config.Backend.GitHub.BaseURL, "GitHub Enterprise Server API base URL (e.g. \"https://github.example.com/api/v3/\")")
+	flags.StringVar(&config.Backend.GitHub.UploadURL, "githubUploadURL", config.Backend.GitHub.UploadURL, "GitHub Enterprise Server upload URL (defaults to /api/uploads/ on the host of the API base URL)")
+	flags.StringVar(&config.Backend.GitHub.Token, "githubToken", config.Backend.GitHub.Token, "GitHub personal access token (falls back to $GITHUB_TOKEN)")
+	flags.StringVar(&config.Backend.GitHub.TokenFile, "githubTokenFile", config.Backend.GitHub.TokenFile, "File containing the GitHub personal access token")
+	flags.Int64Var(&config.Backend.GitHub.AppID, "githubAppID", config.Backend.GitHub.AppID, "GitHub App ID (enables GitHub App authentication)")
+	flags.Int64Var(&config.Backend.GitHub.AppInstallationID, "githubAppInstallationID", config.Backend.GitHub.AppInstallationID, "GitHub App installation ID")
+	flags.StringVar(&config.Backend.GitHub.AppPrivateKeyFile, "githubAppPrivateKeyFile", config.Backend.GitHub.AppPrivateKeyFile, "File containing the GitHub App private key (PEM)")
+	flags.StringVar(&config.Backend.GitHub.WebhookSecretFile, "githubWebhookSecretFile", config.Backend.GitHub.WebhookSecretFile, "File containing the GitHub webhook secret (enables the webhook on \"/.internal/webhooks/github\")")
+	flags.Float64Var(&config.Backend.GitHub.RequestRate, "githubRequestRate", config.Backend.GitHub.RequestRate, "Max. request rate to GitHub")
+	flags.IntVar(&config.Backend.GitHub.BucketSize, "githubBucketSize", config.Backend.GitHub.BucketSize, "Max. request bucket size for GitHub")
+	flags.StringVar(&config.Backend.GitLab.BaseURL, "gitlabBaseURL", config.Backend.GitLab.BaseURL, "GitLab base URL")
+	flags.StringVar(&config.Backend.GitLab.Group, "gitlabGroup", config.Backend.GitLab.Group, "GitLab group, including subgroups (e.g. \"group/subgroup\")")
+	flags.StringVar(&config.Backend.GitLab.Token, "gitlabToken", config.Backend.GitLab.Token, "GitLab access token (falls back to $GITLAB_TOKEN)")
+	flags.Float64Var(&config.Backend.GitLab.RequestRate, "gitlabRequestRate", config.Backend.GitLab.RequestRate, "Max. request rate to GitLab")
+	flags.IntVar(&config.Backend.GitLab.BucketSize, "gitlabBucketSize", config.Backend.GitLab.BucketSize, "Max. request bucket size for GitLab")
+	flags.StringVar(&config.Backend.Gitea.BaseURL, "giteaBaseURL", config.Backend.Gitea.BaseURL, "Gitea/Forgejo base URL")
+	flags.StringVar(&config.Backend.Gitea.Owner, "giteaOwner", config.Backend.Gitea.Owner, "Gitea/Forgejo owner (user or organization)")
+	flags.StringVar(&config.Backend.Gitea.Token, "giteaToken", config.Backend.Gitea.Token, "Gitea/Forgejo access token (falls back to $GITEA_TOKEN)")
+	flags.Float64Var(&config.Backend.Gitea.RequestRate, "giteaRequestRate", config.Backend.Gitea.RequestRate, "Max. request rate to Gitea/Forgejo")
+	flags.IntVar(&config.Backend.Gitea.BucketSize, "giteaBucketSize", config.Backend.Gitea.BucketSize, "Max. request bucket size for Gitea/Forgejo")
+
+	return flags
+}
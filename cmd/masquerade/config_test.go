@@ -0,0 +1,331 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "masquerade.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func lookupEnvFrom(env map[string]string) func(key string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func Test_loadConfig(t *testing.T) {
+	file := writeConfigFile(t, `
+listener:
+  package_host: go.example.com
+cache:
+  ttl: 2h
+backend:
+  github:
+    owner: file-owner
+`)
+
+	tests := []struct {
+		name       string
+		args       []string
+		env        map[string]string
+		wantErr    string
+		wantConfig func(config *Config) bool
+	}{
+		{
+			name: "flags only",
+			args: []string{"-packageHost", "go.example.com", "-githubOwner", "flag-owner"},
+			wantConfig: func(config *Config) bool {
+				return config.Backend.GitHub.Owner == "flag-owner" && config.Cache.TTL == time.Hour && config.Listener.Addr == ":8493"
+			},
+		},
+		{
+			name: "file",
+			args: []string{"-config", file},
+			wantConfig: func(config *Config) bool {
+				return config.Backend.GitHub.Owner == "file-owner" && config.Cache.TTL == 2*time.Hour && config.Cache.NegativeTTL == time.Minute
+			},
+		},
+		{
+			name: "environment overrides file",
+			args: []string{"-config", file},
+			env:  map[string]string{"MASQUERADE_BACKEND_GITHUB_OWNER": "env-owner", "MASQUERADE_CACHE_TTL": "3h", "MASQUERADE_METRICS_ENABLED": "true"},
+			wantConfig: func(config *Config) bool {
+				return config.Backend.GitHub.Owner == "env-owner" && config.Cache.TTL == 3*time.Hour && config.Metrics.Enabled
+			},
+		},
+		{
+			name: "flags override environment and file",
+			args: []string{"-githubOwner", "flag-owner", "-config", file},
+			env:  map[string]string{"MASQUERADE_BACKEND_GITHUB_OWNER": "env-owner"},
+			wantConfig: func(config *Config) bool {
+				return config.Backend.GitHub.Owner == "flag-owner" && config.Cache.TTL == 2*time.Hour
+			},
+		},
+		{
+			name:    "invalid environment variable",
+			args:    []string{"-config", file},
+			env:     map[string]string{"MASQUERADE_CACHE_MAX_ENTRIES": "many"},
+			wantErr: "environment variable MASQUERADE_CACHE_MAX_ENTRIES",
+		},
+		{
+			name:    "missing file",
+			args:    []string{"-config", filepath.Join(t.TempDir(), "missing.yaml")},
+			wantErr: "no such file",
+		},
+		{
+			name:    "invalid flag",
+			args:    []string{"-unknown"},
+			wantErr: "flag provided but not defined",
+		},
+		{
+			name:    "invalid configuration",
+			args:    []string{},
+			wantErr: "listener.package_host is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := loadConfig(tt.args, lookupEnvFrom(tt.env), io.Discard)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("wrong error: got %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if !tt.wantConfig(config) {
+				t.Errorf("wrong config: %+v", config)
+			}
+		})
+	}
+}
+
+func TestConfig_readFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "empty", content: ""},
+		{name: "unknown key", content: "listener:\n  port: 8080\n", wantErr: "line 2: field port not found"},
+		{name: "invalid duration", content: "cache:\n  ttl: soon\n", wantErr: "line 2"},
+		{name: "duration without unit", content: "cache:\n  ttl: 3600\n", wantErr: "line 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := defaultConfig().readFile(writeConfigFile(t, tt.content))
+
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("wrong error: got %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfig_readFile_example(t *testing.T) {
+	config := defaultConfig()
+
+	if err := config.readFile("../../masquerade.example.yaml"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := config.Validate(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	valid := func() *Config {
+		config := defaultConfig()
+		config.Listener.PackageHost = "go.example.com"
+		config.Backend.GitHub.Owner = "the-owner"
+		return config
+	}
+
+	tests := []struct {
+		name     string
+		modify   func(config *Config)
+		wantErrs []string
+	}{
+		{
+			name:   "valid",
+			modify: func(config *Config) {},
+		},
+		{
+			name: "missing listener settings",
+			modify: func(config *Config) {
+				config.Listener.Addr = ""
+				config.Listener.PackageHost = ""
+			},
+			wantErrs: []string{"listener.addr is required", "listener.package_host is required"},
+		},
+		{
+			name: "relative home page URL",
+			modify: func(config *Config) {
+				config.Listener.HomePageURL = "/home"
+			},
+			wantErrs: []string{"listener.home_page_url must be an absolute URL"},
+		},
+		{
+			name: "admin API without token",
+			modify: func(config *Config) {
+				config.Admin.Addr = "127.0.0.1:9092"
+			},
+			wantErrs: []string{"admin.token_file is required"},
+		},
+		{
+			name: "unknown backends",
+			modify: func(config *Config) {
+				config.Cache.Backend = "memcached"
+				config.Backend.Type = "svn"
+				config.Backend.ModulePathCheck = "strict"
+			},
+			wantErrs: []string{`cache.backend must be one of ["memory" "bolt" "redis"], got "memcached"`, "backend.type must be one of", "backend.module_path_check must be one of"},
+		},
+		{
+			name: "invalid durations",
+			modify: func(config *Config) {
+				config.Cache.TTL = 0
+				config.Cache.MaxStale = -time.Second
+				config.Resilience.BreakerThreshold = -1
+			},
+			wantErrs: []string{"cache.ttl must be positive", "cache.max_stale must not be negative", "resilience.breaker_threshold must not be negative"},
+		},
+		{
+			name: "incomplete GitHub App",
+			modify: func(config *Config) {
+				config.Backend.GitHub.AppID = 1
+			},
+			wantErrs: []string{"backend.github.app_id requires"},
+		},
+		{
+			name: "gitlab without group",
+			modify: func(config *Config) {
+				config.Backend.Type = "gitlab"
+			},
+			wantErrs: []string{"backend.gitlab.group is required"},
+		},
+		{
+			name: "gitea without base URL and owner",
+			modify: func(config *Config) {
+				config.Backend.Type = "gitea"
+				config.Backend.Gitea.RequestRate = 0
+			},
+			wantErrs: []string{"backend.gitea.base_url is required", "backend.gitea.owner is required", "backend.gitea.request_rate"},
+		},
+		{
+			name: "webhook without github",
+			modify: func(config *Config) {
+				config.Backend.Type = "gitlab"
+				config.Backend.GitLab.Group = "the-group"
+				config.Backend.GitHub.WebhookSecretFile = "secret"
+			},
+			wantErrs: []string{"backend.github.webhook_secret_file requires the github backend"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid()
+			tt.modify(config)
+
+			err := config.Validate()
+
+			if len(tt.wantErrs) == 0 && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+
+			if len(tt.wantErrs) > 0 && err == nil {
+				t.Fatal("no error")
+			}
+
+			for _, wantErr := range tt.wantErrs {
+				if !strings.Contains(err.Error(), wantErr) {
+					t.Errorf("wrong error: got %q, want %q", err, wantErr)
+				}
+			}
+		})
+	}
+}
+
+func Test_diffConfig(t *testing.T) {
+	old := defaultConfig()
+	new := defaultConfig()
+	new.Listener.PackageHost = "go.example.com"
+	new.Cache.TTL = 2 * time.Hour
+	new.Backend.GitHub.Token = "secret"
+	new.Metrics.Enabled = true
+
+	want := []string{
+		`listener.package_host: "" -> "go.example.com"`,
+		"metrics.enabled: false -> true",
+		"cache.ttl: 1h0m0s -> 2h0m0s",
+		"backend.github.token: changed",
+	}
+
+	changes := diffConfig(old, new)
+	if len(changes) != len(want) {
+		t.Fatalf("wrong changes: %v", changes)
+	}
+
+	for i, change := range changes {
+		if change.String() != want[i] {
+			t.Errorf("wrong change: got %q, want %q", change, want[i])
+		}
+	}
+}
+
+func TestConfig_withReloadable(t *testing.T) {
+	current := defaultConfig()
+	current.Backend.GitHub.WebhookSecretFile = "current-secret"
+
+	next := defaultConfig()
+	next.Listener.Addr = ":9999"
+	next.Listener.HomePageURL = "https://www.example.com"
+	next.Cache.Backend = "redis"
+	next.Cache.NegativeTTL = time.Hour
+	next.Backend.GitHub.Owner = "next-owner"
+	next.Backend.GitHub.WebhookSecretFile = "next-secret"
+	next.Templates.GoGet = "go-get.tmpl"
+
+	merged := current.withReloadable(next)
+
+	var keys []string
+	for _, change := range diffConfig(merged, next) {
+		keys = append(keys, change.key)
+	}
+
+	if want := []string{"listener.addr", "cache.backend", "backend.github.webhook_secret_file"}; !slices.Equal(keys, want) {
+		t.Errorf("settings requiring a restart: got %v, want %v", keys, want)
+	}
+
+	if current.Backend.GitHub.Owner != "" {
+		t.Error("current config modified")
+	}
+}
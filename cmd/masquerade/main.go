@@ -5,21 +5,29 @@ import (
 	"errors"
 	"flag"
 	"fmt"
-	githubClient "github.com/google/go-github/v52/github"
-	"github.com/kofalt/go-memoize"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.eigsys.de/masquerade/pkg/cache"
+	"go.eigsys.de/masquerade/pkg/gitea"
 	"go.eigsys.de/masquerade/pkg/github"
+	"go.eigsys.de/masquerade/pkg/gitlab"
 	"go.eigsys.de/masquerade/pkg/goget"
+	"go.eigsys.de/masquerade/pkg/goproxy"
+	"go.eigsys.de/masquerade/pkg/importpath"
 	"go.eigsys.de/masquerade/pkg/repository"
+	"go.eigsys.de/masquerade/pkg/resilience"
+	"golang.org/x/mod/module"
 	"golang.org/x/time/rate"
 	"io"
 	"log"
+	"math"
 	"net/http"
 	"os"
 	"os/signal"
 	"path"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
@@ -29,6 +37,30 @@ type VCSHandler interface {
 	Fetch(ctx context.Context, repo string) (repository.Repository, error)
 }
 
+type DirectoryChecker interface {
+	HasDirectory(ctx context.Context, repo, dir string) (bool, error)
+}
+
+type ModuleLister interface {
+	Modules(ctx context.Context, repo string) ([]string, error)
+}
+
+type MajorVersionResolver interface {
+	MajorVersionLayout(ctx context.Context, repo, major string) (repository.MajorVersionLayout, error)
+}
+
+type Revalidator interface {
+	Revalidate(ctx context.Context, repo string, previous repository.Repository) (repository.Repository, error)
+}
+
+type ModulePathDeclarer interface {
+	GetModulePath() string
+}
+
+type RateLimitReporter interface {
+	RateLimitRemaining() (int, bool)
+}
+
 type ResponseBuilder interface {
 	Build(writer io.Writer, data *goget.TemplateData) error
 }
@@ -37,11 +69,85 @@ type Memoizer interface {
 	Memoize(key string, fn func() (any, error)) (any, error, bool)
 }
 
-const moduleLabel = "module"
+type StatusMemoizer interface {
+	MemoizeStatus(key string, fn func() (any, error)) (any, error, cache.Status)
+}
+
+type MemoryStatsReporter interface {
+	Stats() cache.MemoryStats
+}
+
+type CoalescedCounter interface {
+	Coalesced() uint64
+}
+
+type RevalidatingMemoizer interface {
+	MemoizeRevalidate(key string, fn func(previous any) (any, error)) (any, error, cache.Status)
+}
+
+const (
+	moduleLabel      = "module"
+	cacheResultLabel = "result"
+	entryKindLabel   = "kind"
+	errorClassLabel  = "class"
+)
+
+const (
+	modulePathCheckOff    = "off"
+	modulePathCheckLog    = "log"
+	modulePathCheckWarn   = "warn"
+	modulePathCheckRefuse = "refuse"
+)
+
+var errModulePathMismatch = errors.New("module path mismatch")
+
+// The timeouts are variables, so that tests can shorten them.
+var (
+	backendTimeout = 30 * time.Second
+	// requestTimeout bounds reading a request and writing its response, besides waiting for the backend.
+	requestTimeout = 6 * time.Second
+)
+
+type errorClass struct {
+	name    string
+	err     error
+	code    int
+	message string
+}
+
+var errorClasses = []errorClass{
+	{name: "not_found", err: repository.ErrNotFound, code: http.StatusNotFound, message: "module not found"},
+	{name: "module_path_mismatch", err: errModulePathMismatch, code: http.StatusNotFound, message: "module path mismatch"},
+	{name: "invalid", err: importpath.ErrInvalid, code: http.StatusBadRequest, message: "bad request"},
+	{name: "invalid", err: repository.ErrInvalidName, code: http.StatusBadRequest, message: "bad request"},
+	{name: "forbidden", err: repository.ErrForbidden, code: http.StatusForbidden, message: "forbidden"},
+	{name: "rate_limited", err: repository.ErrRateLimited, code: http.StatusTooManyRequests, message: "too many requests"},
+	{name: "unavailable", err: repository.ErrUnavailable, code: http.StatusServiceUnavailable, message: "service unavailable"},
+	{name: "timeout", err: repository.ErrTimeout, code: http.StatusGatewayTimeout, message: "gateway timeout"},
+	{name: "timeout", err: context.DeadlineExceeded, code: http.StatusGatewayTimeout, message: "gateway timeout"},
+	{name: "canceled", err: context.Canceled, code: http.StatusGatewayTimeout, message: "gateway timeout"},
+}
+
+var errorClassUpstream = errorClass{name: "upstream", code: http.StatusBadGateway, message: "bad gateway"}
+
+func classifyError(err error) errorClass {
+	for _, class := range errorClasses {
+		if errors.Is(err, class.err) {
+			return class
+		}
+	}
+
+	return errorClassUpstream
+}
 
 type Metrics struct {
-	HTTPRequestsTotal *prometheus.CounterVec
-	ModuleNotFound    prometheus.Counter
+	HTTPRequestsTotal  *prometheus.CounterVec
+	ModuleNotFound     prometheus.Counter
+	ModulePathMismatch *prometheus.CounterVec
+	CacheResults       *prometheus.CounterVec
+	AdminActions       *prometheus.CounterVec
+	Errors             *prometheus.CounterVec
+	Addr               string
 
 	enabled    bool
 	registerer prometheus.Registerer
@@ -49,39 +155,138 @@ type Metrics struct {
 	server     *http.Server
 }
 
-func NewMetrics(enabled bool, registerer prometheus.Registerer, gatherer prometheus.Gatherer) *Metrics {
-	metrics := &Metrics{
-		HTTPRequestsTotal: prometheus.V2.NewCounterVec(
-			prometheus.CounterVecOpts{
-				CounterOpts: prometheus.CounterOpts{
-					Name: "http_requests_total",
-					Help: "Total number of HTTP requests",
-				},
-				VariableLabels: prometheus.ConstrainedLabels{
-					prometheus.ConstrainedLabel{
-						Name: moduleLabel,
-						Constraint: func(s string) string {
-							return strings.ToLower(s)
-						},
+func newModuleCounterVec(name, help string) *prometheus.CounterVec {
+	return prometheus.V2.NewCounterVec(
+		prometheus.CounterVecOpts{
+			CounterOpts: prometheus.CounterOpts{
+				Name: name,
+				Help: help,
+			},
+			VariableLabels: prometheus.ConstrainedLabels{
+				prometheus.ConstrainedLabel{
+					Name: moduleLabel,
+					Constraint: func(s string) string {
+						return strings.ToLower(s)
 					},
 				},
 			},
-		),
+		},
+	)
+}
+
+func NewMetrics(enabled bool, registerer prometheus.Registerer, gatherer prometheus.Gatherer) *Metrics {
+	metrics := &Metrics{
+		HTTPRequestsTotal: newModuleCounterVec("http_requests_total", "Total number of HTTP requests"),
 		ModuleNotFound: prometheus.NewCounter(
 			prometheus.CounterOpts{
 				Name: "module_not_found_total",
 				Help: "Total number of module not found responses",
 			}),
+		ModulePathMismatch: newModuleCounterVec("module_path_mismatch_total", "Total number of responses for modules whose go.mod declares a different module path"),
+		CacheResults: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "cache_results_total",
+				Help: "Total number of repository cache lookups by result (hit, negative_hit, stale or miss)",
+			}, []string{cacheResultLabel}),
+		AdminActions: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "admin_actions_total",
+				Help: "Total number of admin API actions",
+			}, []string{adminActionLabel}),
+		Errors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "errors_total",
+				Help: "Total number of failed requests by error class",
+			}, []string{errorClassLabel}),
+		Addr:       ":9091",
 		enabled:    enabled,
 		registerer: registerer,
 		gatherer:   gatherer,
 	}
 
-	registerer.MustRegister(metrics.HTTPRequestsTotal, metrics.ModuleNotFound)
+	registerer.MustRegister(metrics.HTTPRequestsTotal, metrics.ModuleNotFound, metrics.ModulePathMismatch, metrics.CacheResults, metrics.AdminActions, metrics.Errors)
 
 	return metrics
 }
 
+func (m *Metrics) RegisterRateLimitReporter(reporter RateLimitReporter) {
+	m.registerer.MustRegister(prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "github_rate_limit_remaining",
+			Help: "Remaining requests of the GitHub API rate limit (NaN until the first response, or for other VCS backends)",
+		}, func() float64 {
+			remaining, ok := reporter.RateLimitRemaining()
+			if !ok {
+				return math.NaN()
+			}
+
+			return float64(remaining)
+		}))
+}
+
+func (m *Metrics) RegisterMemoryStats(reporter MemoryStatsReporter) {
+	m.registerer.MustRegister(
+		prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{
+				Name: "cache_entries",
+				Help: "Number of cache entries",
+			}, func() float64 {
+				return float64(reporter.Stats().Entries)
+			}),
+		prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{
+				Name:        "cache_stored_entries",
+				Help:        "Number of cache entries by kind (positive: a repository, negative: a missing repository)",
+				ConstLabels: prometheus.Labels{entryKindLabel: "positive"},
+			}, func() float64 {
+				stats := reporter.Stats()
+				return float64(stats.Entries - stats.NegativeEntries)
+			}),
+		prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{
+				Name:        "cache_stored_entries",
+				Help:        "Number of cache entries by kind (positive: a repository, negative: a missing repository)",
+				ConstLabels: prometheus.Labels{entryKindLabel: "negative"},
+			}, func() float64 {
+				return float64(reporter.Stats().NegativeEntries)
+			}),
+		prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{
+				Name: "cache_bytes",
+				Help: "Approximate size of all cache entries in bytes",
+			}, func() float64 {
+				return float64(reporter.Stats().Bytes)
+			}),
+		prometheus.NewCounterFunc(
+			prometheus.CounterOpts{
+				Name: "cache_evictions_total",
+				Help: "Total number of cache entries evicted because the cache was full",
+			}, func() float64 {
+				return float64(reporter.Stats().Evictions)
+			}),
+	)
+}
+
+func (m *Metrics) RegisterCoalescedCounter(counter CoalescedCounter) {
+	m.registerer.MustRegister(prometheus.NewCounterFunc(
+		prometheus.CounterOpts{
+			Name: "cache_coalesced_requests_total",
+			Help: "Total number of cache misses that waited for a concurrent fetch of the same key instead of fetching it again",
+		}, func() float64 {
+			return float64(counter.Coalesced())
+		}))
+}
+
+func (m *Metrics) RegisterCircuitBreaker(breaker *resilience.CircuitBreaker) {
+	m.registerer.MustRegister(prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "circuit_breaker_state",
+			Help: "State of the circuit breaker around the VCS backend (0: closed, 1: half-open, 2: open)",
+		}, func() float64 {
+			return float64(breaker.State())
+		}))
+}
+
 func (m *Metrics) ListenAndServe() error {
 	if !m.enabled {
 		return nil
@@ -91,7 +296,7 @@ func (m *Metrics) ListenAndServe() error {
 	mux.Handle("/metrics", promhttp.HandlerFor(m.gatherer, promhttp.HandlerOpts{Registry: m.registerer}))
 
 	m.server = &http.Server{
-		Addr:         ":9091",
+		Addr:         m.Addr,
 		Handler:      mux,
 		ReadTimeout:  3 * time.Second,
 		WriteTimeout: 3 * time.Second,
@@ -103,16 +308,30 @@ func (m *Metrics) ListenAndServe() error {
 }
 
 type AppContext struct {
-	Metrics         *Metrics
-	VCSHandler      VCSHandler
-	ResponseBuilder ResponseBuilder
-	Cache           Memoizer
-	PackageHost     string
-	ServerAddr      string
-	MaxAge          time.Duration
-	HomePageURL     string
-
-	server *http.Server
+	Metrics          *Metrics
+	VCSHandler       VCSHandler
+	ResponseBuilder  ResponseBuilder
+	Cache            Memoizer
+	PackageHost      string
+	ServerAddr       string
+	MaxAge           time.Duration
+	HomePageURL      string
+	ValidateSubpaths bool
+	ModulePathCheck  string
+	Proxy            http.Handler
+	Webhook          http.Handler
+	AdminAddr        string
+	AdminToken       string
+	Retrier          *resilience.Retrier
+	Breaker          *resilience.CircuitBreaker
+	LoadConfig       func() (*Config, error)
+
+	config         *Config
+	reloaded       atomic.Pointer[AppContext]
+	stopPrewarming context.CancelFunc
+	server         *http.Server
+	adminServer    *http.Server
+	ready          atomic.Bool
 }
 
 func (a *AppContext) ListenAndServe() error {
@@ -122,24 +341,47 @@ func (a *AppContext) ListenAndServe() error {
 		}
 	}()
 
-	a.server = &http.Server{
-		Addr:         a.ServerAddr,
-		Handler:      a.getMux(),
-		ReadTimeout:  6 * time.Second,
-		WriteTimeout: 6 * time.Second,
-	}
+	go func() {
+		if err := a.ListenAndServeAdmin(); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	a.server = newServer(a.ServerAddr, a.getMux())
 
 	log.Printf("listening on %q", a.server.Addr)
 
 	return a.server.ListenAndServe()
 }
 
+// newServer returns a server whose responses may wait for the backend, including retries, until backendTimeout, so
+// that even errors like "504 Gateway Timeout" reach the client.
+func newServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:         addr,
+		Handler:      handler,
+		ReadTimeout:  requestTimeout,
+		WriteTimeout: backendTimeout + requestTimeout,
+	}
+}
+
 func (a *AppContext) GracefulShutdown() {
 	quit := make(chan os.Signal, 1)
 
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+
+	for sig := range quit {
+		if sig != syscall.SIGHUP {
+			break
+		}
+
+		log.Print("reloading the configuration")
+
+		if err := a.Reload(); err != nil {
+			log.Printf("config: keeping the current configuration: %s", err)
+		}
+	}
 
-	<-quit
 	log.Print("shutting down")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 12*time.Second)
@@ -152,59 +394,264 @@ func (a *AppContext) GracefulShutdown() {
 
 func (a *AppContext) getMux() http.Handler {
 	mux := http.NewServeMux()
-	mux.HandleFunc("/", a.handleCacheControlHeader(a.handleRequest))
+	mux.HandleFunc("/", a.serveCurrent(func(c *AppContext) http.HandlerFunc {
+		return c.handleCacheControlHeader(c.handleRequest)
+	}))
 	mux.HandleFunc("/.internal/health", a.handleHealth)
+	mux.HandleFunc("/.internal/ready", a.handleReady)
+
+	if a.Webhook != nil {
+		mux.HandleFunc("POST /.internal/webhooks/github", a.serveCurrent(func(c *AppContext) http.HandlerFunc {
+			return c.Webhook.ServeHTTP
+		}))
+	}
+
+	if a.Proxy != nil {
+		mux.HandleFunc("/"+a.PackageHost+"/", a.serveCurrent(func(c *AppContext) http.HandlerFunc {
+			return c.handleCacheControlHeader(c.handleProxyRequest)
+		}))
+	}
 
 	return mux
 }
 
 func (a *AppContext) buildResponse(response http.ResponseWriter, request *http.Request) error {
-	repo := strings.Split(request.URL.Path, "/")[1]
+	importPath, err := importpath.Parse(request.URL.Path)
+	if err != nil {
+		return err
+	}
+
+	repo := importPath.Repo
 
 	if repo == "" && a.HomePageURL != "" {
 		http.Redirect(response, request, a.HomePageURL, http.StatusSeeOther)
 		return nil
 	}
 
-	vcsData, err, cached := a.Cache.Memoize(repo, func() (any, error) {
-		return a.VCSHandler.Fetch(request.Context(), repo)
-	})
+	vcsData, err, cacheStatus := a.fetchRepository(request.Context(), repo)
+	if errors.Is(err, repository.ErrNotFound) {
+		a.handleXCacheHeader(response, cacheStatus)
+	}
 	if err != nil {
 		return err
 	}
 
-	handleXCacheHeader(response, cached)
-	a.Metrics.HTTPRequestsTotal.With(prometheus.Labels{moduleLabel: repo}).Inc()
+	if majorVersionLayout, ok := a.resolveMajorVersionLayout(request.Context(), importPath); ok {
+		if err := a.validateSubpath(request.Context(), repo, subpathDir(importPath, majorVersionLayout)); err != nil {
+			return err
+		}
+	}
+
+	moduleDir, err := a.resolveModuleDir(request.Context(), importPath)
+	if err != nil {
+		return err
+	}
 
 	vcsRepository := vcsData.(repository.Repository)
+	importPrefix := path.Join(a.PackageHost, repo, moduleDir)
+
+	if err := a.checkModulePath(response, vcsRepository, repo, importPrefix, moduleDir); err != nil {
+		return err
+	}
+
+	a.handleXCacheHeader(response, cacheStatus)
+	a.Metrics.HTTPRequestsTotal.With(prometheus.Labels{moduleLabel: repo}).Inc()
+
+	sourceURLs := vcsRepository.GetSourceURLs(moduleDir)
 	data := &goget.TemplateData{
-		ImportPrefix:   path.Join(a.PackageHost, repo),
-		VCS:            a.VCSHandler.Type(),
-		RepoRoot:       vcsRepository.GetRepoRoot(),
-		ProjectWebsite: vcsRepository.GetProjectWebsiteOrFallback(vcsRepository.GetRepoRoot()),
+		ImportPrefix:    importPrefix,
+		VCS:             a.VCSHandler.Type(),
+		RepoRoot:        vcsRepository.GetRepoRoot(),
+		Subdirectory:    moduleDir,
+		SourceHome:      sourceURLs.Home,
+		SourceDirectory: sourceURLs.Directory,
+		SourceFile:      sourceURLs.File,
+		ProjectWebsite:  vcsRepository.GetProjectWebsiteOrFallback(vcsRepository.GetRepoRoot()),
 	}
 
 	return a.ResponseBuilder.Build(response, data)
 }
 
+// resolveMajorVersionLayout returns the layout of the requested major version, which is only needed to validate
+// sub-packages. It returns false if the layout can't be determined (e.g. a major version that only exists as tags),
+// so that the sub-package isn't validated.
+func (a *AppContext) resolveMajorVersionLayout(ctx context.Context, importPath *importpath.ImportPath) (repository.MajorVersionLayout, bool) {
+	if importPath.Major == "" {
+		return "", true
+	}
+
+	if !a.ValidateSubpaths || importPath.Subpath == "" {
+		return "", false
+	}
+
+	majorVersionResolver, ok := a.VCSHandler.(MajorVersionResolver)
+	if !ok {
+		return "", true
+	}
+
+	layout, err, _ := a.Cache.Memoize(cache.Key(importPath.Repo, "major", importPath.Major), cache.Detach(ctx, backendTimeout, func(ctx context.Context) (any, error) {
+		return majorVersionResolver.MajorVersionLayout(ctx, importPath.Repo, importPath.Major)
+	}))
+	if err != nil {
+		if !errors.Is(err, repository.ErrNotFound) {
+			log.Printf("resolving the layout of %s/%s: %s", importPath.Repo, importPath.Major, err)
+		}
+
+		return "", false
+	}
+
+	return layout.(repository.MajorVersionLayout), true
+}
+
+// subpathDir returns the directory of the requested sub-package on the default branch, or "" if it can't be checked
+// there, because the major version lives on a branch of its own.
+func subpathDir(importPath *importpath.ImportPath, majorVersionLayout repository.MajorVersionLayout) string {
+	switch majorVersionLayout {
+	case repository.MajorVersionBranch:
+		return ""
+	case repository.MajorVersionSubdirectory:
+		if importPath.Subpath == "" {
+			return ""
+		}
+
+		return path.Join(importPath.Major, importPath.Subpath)
+	}
+
+	return importPath.Subpath
+}
+
+func (a *AppContext) validateSubpath(ctx context.Context, repo, dir string) error {
+	if !a.ValidateSubpaths || dir == "" {
+		return nil
+	}
+
+	directoryChecker, ok := a.VCSHandler.(DirectoryChecker)
+	if !ok {
+		return nil
+	}
+
+	exists, err, _ := a.Cache.Memoize(cache.Key(repo, "dir", dir), cache.Detach(ctx, backendTimeout, func(ctx context.Context) (any, error) {
+		return directoryChecker.HasDirectory(ctx, repo, dir)
+	}))
+	if err != nil {
+		return err
+	}
+
+	if !exists.(bool) {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+func (a *AppContext) resolveModuleDir(ctx context.Context, importPath *importpath.ImportPath) (string, error) {
+	if importPath.Subpath == "" || importPath.Major != "" {
+		return "", nil
+	}
+
+	moduleLister, ok := a.VCSHandler.(ModuleLister)
+	if !ok {
+		return "", nil
+	}
+
+	modules, err, _ := a.Cache.Memoize(cache.Key(importPath.Repo, "modules"), cache.Detach(ctx, backendTimeout, func(ctx context.Context) (any, error) {
+		return moduleLister.Modules(ctx, importPath.Repo)
+	}))
+	if err != nil {
+		return "", err
+	}
+
+	return findModuleDir(modules.([]string), importPath.Subpath), nil
+}
+
+func findModuleDir(modules []string, subpath string) string {
+	moduleDir := ""
+
+	for _, module := range modules {
+		if (subpath == module || strings.HasPrefix(subpath, module+"/")) && len(module) > len(moduleDir) {
+			moduleDir = module
+		}
+	}
+
+	return moduleDir
+}
+
+func (a *AppContext) checkModulePath(response http.ResponseWriter, vcsRepository repository.Repository, repo, importPrefix, moduleDir string) error {
+	if a.ModulePathCheck == "" || a.ModulePathCheck == modulePathCheckOff || moduleDir != "" {
+		return nil
+	}
+
+	modulePathDeclarer, ok := vcsRepository.(ModulePathDeclarer)
+	if !ok {
+		return nil
+	}
+
+	modulePath := modulePathDeclarer.GetModulePath()
+	if modulePath == "" || isMatchingModulePath(modulePath, importPrefix) {
+		return nil
+	}
+
+	a.Metrics.ModulePathMismatch.With(prometheus.Labels{moduleLabel: repo}).Inc()
+	log.Printf("module path mismatch: go.mod of %q declares %q", importPrefix, modulePath)
+
+	switch a.ModulePathCheck {
+	case modulePathCheckWarn:
+		response.Header().Add("Warning", fmt.Sprintf("299 - %s", strconv.Quote("go.mod declares module path "+modulePath)))
+	case modulePathCheckRefuse:
+		return fmt.Errorf("%w: go.mod of %q declares %q", errModulePathMismatch, importPrefix, modulePath)
+	}
+
+	return nil
+}
+
+func isMatchingModulePath(modulePath, importPrefix string) bool {
+	if modulePath == importPrefix {
+		return true
+	}
+
+	prefix, major, ok := module.SplitPathVersion(modulePath)
+
+	return ok && major != "" && prefix == importPrefix
+}
+
 func (a *AppContext) handleRequest(response http.ResponseWriter, request *http.Request) {
 	if err := a.buildResponse(response, request); err != nil {
-		log.Print(err)
+		a.handleError(response, request, err)
+	}
+}
 
-		if errors.Is(err, repository.ErrNotFound) {
-			a.Metrics.ModuleNotFound.Inc()
-			http.Error(response, "module not found", http.StatusNotFound)
-			return
-		}
+// handleError responds with the status code of the error class of err, which is shared by go-get and proxy requests.
+func (a *AppContext) handleError(response http.ResponseWriter, _ *http.Request, err error) {
+	log.Print(err)
 
-		http.Error(response, "bad request", http.StatusBadRequest)
+	class := classifyError(err)
+	a.Metrics.Errors.With(prometheus.Labels{errorClassLabel: class.name}).Inc()
+
+	if errors.Is(err, repository.ErrNotFound) {
+		a.Metrics.ModuleNotFound.Inc()
+	}
+
+	var retryAfterErr *repository.RetryAfterError
+	if errors.As(err, &retryAfterErr) && retryAfterErr.RetryAfter > 0 {
+		response.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfterErr.RetryAfter.Seconds()))))
 	}
+
+	http.Error(response, class.message, class.code)
 }
 
 func (a *AppContext) handleHealth(response http.ResponseWriter, _ *http.Request) {
 	_, _ = fmt.Fprint(response, "ok")
 }
 
+func (a *AppContext) handleProxyRequest(response http.ResponseWriter, request *http.Request) {
+	if !goproxy.IsProxyRequest(request.URL.Path) {
+		a.handleRequest(response, request)
+		return
+	}
+
+	a.Proxy.ServeHTTP(response, request)
+}
+
 func (a *AppContext) handleCacheControlHeader(handler http.HandlerFunc) http.HandlerFunc {
 	return func(response http.ResponseWriter, request *http.Request) {
 		handler(response, request)
@@ -212,46 +659,319 @@ func (a *AppContext) handleCacheControlHeader(handler http.HandlerFunc) http.Han
 	}
 }
 
-func handleXCacheHeader(response http.ResponseWriter, cached bool) {
-	value := "Miss"
-	if cached {
-		value = "Hit"
+func (a *AppContext) fetchRepository(ctx context.Context, repo string) (any, error, cache.Status) {
+	revalidator, ok := a.VCSHandler.(Revalidator)
+	revalidatingMemoizer, memoizerOK := a.Cache.(RevalidatingMemoizer)
+
+	if !ok || !memoizerOK {
+		return a.memoize(repo, cache.Detach(ctx, backendTimeout, a.resilient(func(ctx context.Context) (any, error) {
+			return a.VCSHandler.Fetch(ctx, repo)
+		})))
+	}
+
+	return revalidatingMemoizer.MemoizeRevalidate(repo, func(previous any) (any, error) {
+		previousRepository, _ := previous.(repository.Repository)
+
+		return cache.Detach(ctx, backendTimeout, a.resilient(func(ctx context.Context) (any, error) {
+			return revalidator.Revalidate(ctx, repo, previousRepository)
+		}))()
+	})
+}
+
+// resilient retries fn on transient errors and guards it with the circuit breaker.
+// The retries are bounded by the deadline of the context passed to the returned function.
+func (a *AppContext) resilient(fn func(ctx context.Context) (any, error)) func(ctx context.Context) (any, error) {
+	return func(ctx context.Context) (any, error) {
+		var value any
+
+		err := a.Retrier.Do(ctx, func(ctx context.Context) error {
+			return a.Breaker.Do(func() error {
+				var err error
+				value, err = fn(ctx)
+				return err
+			})
+		})
+
+		return value, err
+	}
+}
+
+func (a *AppContext) memoize(key string, fn func() (any, error)) (any, error, cache.Status) {
+	if statusMemoizer, ok := a.Cache.(StatusMemoizer); ok {
+		return statusMemoizer.MemoizeStatus(key, fn)
+	}
+
+	value, err, cached := a.Cache.Memoize(key, fn)
+
+	switch {
+	case cached && err != nil:
+		return value, err, cache.StatusNegativeHit
+	case cached:
+		return value, err, cache.StatusHit
+	default:
+		return value, err, cache.StatusMiss
+	}
+}
+
+func (a *AppContext) handleXCacheHeader(response http.ResponseWriter, status cache.Status) {
+	response.Header().Add("X-Cache", string(status))
+
+	if status == cache.StatusStale {
+		response.Header().Add("Warning", `110 - "Response is Stale"`)
+	}
+
+	result := strings.ReplaceAll(strings.ToLower(string(status)), "-", "_")
+	a.Metrics.CacheResults.With(prometheus.Labels{cacheResultLabel: result}).Inc()
+}
+
+func resolveSecret(value, envKey, file string) (string, error) {
+	if value != "" {
+		return value, nil
+	}
+
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	return os.Getenv(envKey), nil
+}
+
+func newGitHubTransport(baseURL, token, tokenFile string, appID, installationID int64, privateKeyFile string) (http.RoundTripper, error) {
+	if appID != 0 {
+		if installationID == 0 || privateKeyFile == "" {
+			return nil, errors.New("GitHub App authentication requires an installation ID and a private key file")
+		}
+
+		privateKey, err := os.ReadFile(privateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		return github.NewAppTransport(http.DefaultTransport, baseURL, appID, installationID, privateKey)
+	}
+
+	token, err := resolveSecret(token, "GITHUB_TOKEN", tokenFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if token == "" {
+		return http.DefaultTransport, nil
+	}
+
+	return github.NewTokenTransport(http.DefaultTransport, baseURL, token)
+}
+
+func newVCSHandler(config *BackendConfig) (VCSHandler, error) {
+	switch config.Type {
+	case "github":
+		httpClient := &http.Client{}
+
+		client, err := github.NewClient(config.GitHub.BaseURL, config.GitHub.UploadURL, httpClient)
+		if err != nil {
+			return nil, err
+		}
+
+		transport, err := newGitHubTransport(
+			client.BaseURL.String(),
+			config.GitHub.Token,
+			config.GitHub.TokenFile,
+			config.GitHub.AppID,
+			config.GitHub.AppInstallationID,
+			config.GitHub.AppPrivateKeyFile,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		httpClient.Transport = github.NewConditionalTransport(transport)
+
+		options := []github.Option{github.WithGitService(client.Git), github.WithHTTPClient(httpClient)}
+		if config.ModulePathCheck != modulePathCheckOff {
+			options = append(options, github.WithModulePath(config.ModulePathCheck == modulePathCheckRefuse))
+		}
+
+		return github.New(
+			client.Repositories,
+			rate.NewLimiter(rate.Limit(config.GitHub.RequestRate), config.GitHub.BucketSize),
+			config.GitHub.Owner,
+			options...,
+		), nil
+	case "gitlab":
+		token, err := resolveSecret(config.GitLab.Token, "GITLAB_TOKEN", "")
+		if err != nil {
+			return nil, err
+		}
+
+		client, err := gitlab.NewClient(config.GitLab.BaseURL, nil, token)
+		if err != nil {
+			return nil, err
+		}
+
+		return gitlab.New(
+			client,
+			rate.NewLimiter(rate.Limit(config.GitLab.RequestRate), config.GitLab.BucketSize),
+			config.GitLab.Group,
+		), nil
+	case "gitea":
+		token, err := resolveSecret(config.Gitea.Token, "GITEA_TOKEN", "")
+		if err != nil {
+			return nil, err
+		}
+
+		client, err := gitea.NewClient(config.Gitea.BaseURL, nil, token)
+		if err != nil {
+			return nil, err
+		}
+
+		return gitea.New(
+			client,
+			rate.NewLimiter(rate.Limit(config.Gitea.RequestRate), config.Gitea.BucketSize),
+			config.Gitea.Owner,
+		), nil
+	default:
+		return nil, fmt.Errorf("unknown VCS backend %q", config.Type)
 	}
+}
+
+// newStore returns the cache store, and the in-memory store within it (if any), whose usage is reported as metrics.
+func newStore(config *CacheConfig) (cache.Store, *cache.MemoryStore, error) {
+	switch config.Backend {
+	case "memory":
+		memoryStore := cache.NewMemoryStore(config.TTL, cache.WithMaxEntries(config.MaxEntries), cache.WithMaxBytes(config.MaxBytes))
+
+		return memoryStore, memoryStore, nil
+	case "redis":
+		url, err := resolveSecret(config.Redis.URL, "REDIS_URL", "")
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if url == "" {
+			url = "redis://localhost:6379/0"
+		}
 
-	response.Header().Add("X-Cache", value)
+		redisStore, err := cache.NewRedisStore(url, config.Redis.KeyPrefix)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if config.Redis.LocalTTL <= 0 {
+			return redisStore, nil, nil
+		}
+
+		memoryStore := cache.NewMemoryStore(config.Redis.LocalTTL, cache.WithMaxEntries(config.MaxEntries), cache.WithMaxBytes(config.MaxBytes))
+
+		return cache.NewTieredStore(memoryStore, redisStore, config.Redis.LocalTTL), memoryStore, nil
+	case "bolt":
+		boltStore, err := cache.NewBoltStore(config.Path, config.TTL)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return boltStore, nil, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown cache backend %q", config.Backend)
+	}
+}
+
+func newResponseBuilder(config *TemplatesConfig) (ResponseBuilder, error) {
+	if config.GoGet == "" {
+		return goget.New(), nil
+	}
+
+	text, err := os.ReadFile(config.GoGet)
+	if err != nil {
+		return nil, err
+	}
+
+	responseBuilder, err := goget.NewWithTemplate(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", config.GoGet, err)
+	}
+
+	return responseBuilder, nil
 }
 
 func main() {
-	serverAddr := flag.String("serverAddr", ":8493", "HTTP listener address")
-	packageHost := flag.String("packageHost", "", "Package host")
-	ttl := flag.Duration("ttl", 1*time.Hour, "Cache TTL")
-	homePageURL := flag.String("homePageURL", "", "Home page URL (requesting \"/\") redirects to this URL")
-	githubOwner := flag.String("githubOwner", "", "GitHub owner")
-	githubRequestRate := flag.Float64("githubRequestRate", 25, "Max. request rate to GitHub")
-	githubBucketSize := flag.Int("githubBucketSize", 100, "Max. request bucket size for GitHub")
-	enableMetrics := flag.Bool("enableMetrics", false, "Enable Prometheus metrics on \":9091/metrics\"")
-	flag.Parse()
+	config, err := loadConfig(os.Args[1:], os.LookupEnv, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	if *serverAddr == "" || *packageHost == "" || *githubOwner == "" {
-		flag.Usage()
-		log.Fatal("invalid flag")
+	store, memoryStore, err := newStore(&config.Cache)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	defer func() { _ = store.Close() }()
+
+	cache.Register(&github.Repository{}, &gitlab.Repository{}, &gitea.Repository{}, repository.MajorVersionLayout(""), time.Time{})
+	memoizer := cache.NewMemoizer(store, config.Cache.TTL, config.Cache.NegativeTTL, config.Cache.MaxStale, cache.WithRevalidation(config.Cache.TTL))
+
+	adminToken, err := resolveSecret("", "", config.Admin.TokenFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if config.Admin.Addr != "" && adminToken == "" {
+		log.Fatal("the admin API requires a token file")
 	}
 
 	registry := prometheus.NewRegistry()
 
-	appContext := &AppContext{
-		Metrics: NewMetrics(*enableMetrics, registry, registry),
-		VCSHandler: github.New(
-			githubClient.NewClient(nil).Repositories,
-			rate.NewLimiter(rate.Limit(*githubRequestRate), *githubBucketSize),
-			*githubOwner,
-		),
-		ResponseBuilder: goget.New(),
-		Cache:           memoize.NewMemoizer(*ttl, *ttl),
-		PackageHost:     *packageHost,
-		ServerAddr:      *serverAddr,
-		MaxAge:          *ttl,
-		HomePageURL:     *homePageURL,
+	var breaker *resilience.CircuitBreaker
+	if config.Resilience.BreakerThreshold > 0 {
+		breaker = resilience.NewCircuitBreaker(config.Resilience.BreakerThreshold, config.Resilience.BreakerCooldown)
+	}
+
+	base := &AppContext{
+		Metrics:     NewMetrics(config.Metrics.Enabled, registry, registry),
+		Cache:       memoizer,
+		PackageHost: config.Listener.PackageHost,
+		ServerAddr:  config.Listener.Addr,
+		AdminAddr:   config.Admin.Addr,
+		AdminToken:  adminToken,
+		Retrier:     resilience.NewRetrier(config.Resilience.RetryAttempts, config.Resilience.RetryBaseDelay, config.Resilience.RetryMaxDelay),
+		Breaker:     breaker,
+		LoadConfig: func() (*Config, error) {
+			return loadConfig(os.Args[1:], os.LookupEnv, os.Stderr)
+		},
+	}
+
+	appContext, err := base.withConfig(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	appContext.Metrics.Addr = config.Metrics.Addr
+	appContext.Metrics.RegisterCoalescedCounter(memoizer)
+
+	if memoryStore != nil {
+		appContext.Metrics.RegisterMemoryStats(memoryStore)
+	}
+
+	if breaker != nil {
+		appContext.Metrics.RegisterCircuitBreaker(breaker)
+	}
+
+	// The gauge reads the current VCS backend, which may become GitHub by a reload.
+	appContext.Metrics.RegisterRateLimitReporter(appContext)
+
+	if config.Cache.Prewarm {
+		if err := appContext.startPrewarming(config.Cache.PrewarmInterval); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		appContext.ready.Store(true)
 	}
 
 	go func() {
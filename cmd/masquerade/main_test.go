@@ -4,13 +4,22 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/prometheus/client_model/go"
+	"go.eigsys.de/masquerade/pkg/cache"
 	"go.eigsys.de/masquerade/pkg/goget"
+	"go.eigsys.de/masquerade/pkg/goproxy"
+	"go.eigsys.de/masquerade/pkg/importpath"
 	"go.eigsys.de/masquerade/pkg/repository"
+	"go.eigsys.de/masquerade/pkg/resilience"
 	"io"
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -19,6 +28,8 @@ import (
 type mockRepository struct {
 	RepoRootResult       string
 	ProjectWebsiteResult string
+	SourceURLsResult     repository.SourceURLs
+	SourceURLsDir        string
 }
 
 func (m *mockRepository) GetRepoRoot() string {
@@ -29,10 +40,25 @@ func (m *mockRepository) GetProjectWebsiteOrFallback(_ string) string {
 	return m.ProjectWebsiteResult
 }
 
+func (m *mockRepository) GetSourceURLs(dir string) repository.SourceURLs {
+	m.SourceURLsDir = dir
+	return m.SourceURLsResult
+}
+
+type mockModulePathRepository struct {
+	mockRepository
+	modulePath string
+}
+
+func (m *mockModulePathRepository) GetModulePath() string {
+	return m.modulePath
+}
+
 type mockVCSHandler struct {
 	typeResult  string
 	fetchResult repository.Repository
 	fetchErr    error
+	fetchCalls  int
 }
 
 func (m *mockVCSHandler) Type() string {
@@ -40,15 +66,78 @@ func (m *mockVCSHandler) Type() string {
 }
 
 func (m *mockVCSHandler) Fetch(_ context.Context, _ string) (repository.Repository, error) {
+	m.fetchCalls++
+	return m.fetchResult, m.fetchErr
+}
+
+type mockDirectoryCheckerVCSHandler struct {
+	mockVCSHandler
+	hasDirectoryResult bool
+	hasDirectoryErr    error
+	hasDirectoryCalls  int
+}
+
+func (m *mockDirectoryCheckerVCSHandler) HasDirectory(_ context.Context, _, _ string) (bool, error) {
+	m.hasDirectoryCalls++
+	return m.hasDirectoryResult, m.hasDirectoryErr
+}
+
+type mockRevalidatorVCSHandler struct {
+	mockVCSHandler
+	revalidatePrevious []repository.Repository
+}
+
+func (m *mockRevalidatorVCSHandler) Revalidate(_ context.Context, _ string, previous repository.Repository) (repository.Repository, error) {
+	m.revalidatePrevious = append(m.revalidatePrevious, previous)
+
+	if previous != nil {
+		return previous, nil
+	}
+
 	return m.fetchResult, m.fetchErr
 }
 
+type mockModuleListerVCSHandler struct {
+	mockVCSHandler
+	modulesResult []string
+	modulesErr    error
+}
+
+func (m *mockModuleListerVCSHandler) Modules(_ context.Context, _ string) ([]string, error) {
+	return m.modulesResult, m.modulesErr
+}
+
+type mockMajorVersionResolverVCSHandler struct {
+	mockVCSHandler
+	majorVersionLayoutResult repository.MajorVersionLayout
+	majorVersionLayoutErr    error
+	majorVersionLayoutCalls  int
+	hasDirectoryResult       bool
+	hasDirectoryDir          string
+}
+
+func (m *mockMajorVersionResolverVCSHandler) MajorVersionLayout(_ context.Context, _, _ string) (repository.MajorVersionLayout, error) {
+	m.majorVersionLayoutCalls++
+	return m.majorVersionLayoutResult, m.majorVersionLayoutErr
+}
+
+func (m *mockMajorVersionResolverVCSHandler) HasDirectory(_ context.Context, _, dir string) (bool, error) {
+	m.hasDirectoryDir = dir
+	return m.hasDirectoryResult, nil
+}
+
+func (m *mockMajorVersionResolverVCSHandler) Modules(_ context.Context, _ string) ([]string, error) {
+	return []string{"v2", "pkg"}, nil
+}
+
 type mockResponseBuilder struct {
 	buildBytes []byte
 	buildErr   error
+	buildData  *goget.TemplateData
 }
 
-func (m *mockResponseBuilder) Build(writer io.Writer, _ *goget.TemplateData) error {
+func (m *mockResponseBuilder) Build(writer io.Writer, data *goget.TemplateData) error {
+	m.buildData = data
 	_, _ = writer.Write(m.buildBytes)
 	return m.buildErr
 }
@@ -79,6 +168,130 @@ func (m *mockRegistry) Gather() ([]*io_prometheus_client.MetricFamily, error) {
 	return []*io_prometheus_client.MetricFamily{{}}, nil
 }
 
+type mockRateLimitReporter struct {
+	remaining int
+	ok        bool
+}
+
+func (m *mockRateLimitReporter) RateLimitRemaining() (int, bool) {
+	return m.remaining, m.ok
+}
+
+func TestMetrics_RegisterRateLimitReporter(t *testing.T) {
+	tests := []struct {
+		name     string
+		reporter *mockRateLimitReporter
+		want     float64
+	}{
+		{
+			name:     "known",
+			reporter: &mockRateLimitReporter{remaining: 4321, ok: true},
+			want:     4321,
+		},
+		{
+			name:     "unknown",
+			reporter: &mockRateLimitReporter{},
+			want:     math.NaN(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := prometheus.NewRegistry()
+			metrics := NewMetrics(false, registry, registry)
+
+			metrics.RegisterRateLimitReporter(tt.reporter)
+
+			families, err := registry.Gather()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, family := range families {
+				if family.GetName() != "github_rate_limit_remaining" {
+					continue
+				}
+
+				got := family.GetMetric()[0].GetGauge().GetValue()
+				if got != tt.want && !(math.IsNaN(got) && math.IsNaN(tt.want)) {
+					t.Errorf("github_rate_limit_remaining = %v, want %v", got, tt.want)
+				}
+
+				return
+			}
+
+			t.Error("github_rate_limit_remaining not registered")
+		})
+	}
+}
+
+type mockCoalescedCounter uint64
+
+func (m mockCoalescedCounter) Coalesced() uint64 {
+	return uint64(m)
+}
+
+func TestMetrics_RegisterCoalescedCounter(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics := NewMetrics(false, registry, registry)
+
+	metrics.RegisterCoalescedCounter(mockCoalescedCounter(42))
+
+	if got, err := testutil.GatherAndCount(registry, "cache_coalesced_requests_total"); err != nil || got != 1 {
+		t.Fatalf("GatherAndCount() = %v, %v", got, err)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, family := range families {
+		if family.GetName() == "cache_coalesced_requests_total" && family.GetMetric()[0].GetCounter().GetValue() != 42 {
+			t.Errorf("cache_coalesced_requests_total = %v", family.GetMetric()[0].GetCounter().GetValue())
+		}
+	}
+}
+
+func TestMetrics_RegisterMemoryStats(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics := NewMetrics(false, registry, registry)
+	store := cache.NewMemoryStore(0, cache.WithMaxEntries(1))
+	defer func() { _ = store.Close() }()
+
+	metrics.RegisterMemoryStats(store)
+	_ = store.Set("foo", &cache.Entry{}, time.Hour)
+	_ = store.Set("bar", &cache.Entry{NotFound: true}, time.Hour)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]float64{}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			name := family.GetName()
+			for _, label := range metric.GetLabel() {
+				name += "/" + label.GetValue()
+			}
+
+			got[name] = metric.GetGauge().GetValue() + metric.GetCounter().GetValue()
+		}
+	}
+
+	want := map[string]float64{
+		"cache_entries":                 1,
+		"cache_stored_entries/positive": 0,
+		"cache_stored_entries/negative": 1,
+		"cache_bytes":                   float64(store.Stats().Bytes),
+		"cache_evictions_total":         1,
+		"module_not_found_total":        0,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("metrics = %v, want %v", got, want)
+	}
+}
+
 func Test_appContext_getMux(t *testing.T) {
 	response := httptest.NewRecorder()
 	request := httptest.NewRequest(http.MethodGet, "/foo", nil)
@@ -110,6 +323,146 @@ func Test_appContext_getMux(t *testing.T) {
 	}
 }
 
+func Test_appContext_getMux_proxy(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		wantBody string
+	}{
+		{
+			name:     "proxy-request",
+			path:     "/go.example.com/foo/@v/list",
+			wantBody: "proxy",
+		},
+		{
+			name:     "go-get-request",
+			path:     "/go.example.com/foo",
+			wantBody: "<head>",
+		},
+		{
+			name:     "other-path",
+			path:     "/foo/@v/list",
+			wantBody: "bad request\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			appContext := &AppContext{
+				Metrics:         NewMetrics(false, &mockRegistry{}, &mockRegistry{}),
+				VCSHandler:      &mockVCSHandler{},
+				ResponseBuilder: &mockResponseBuilder{buildBytes: []byte("<head>")},
+				Cache:           &mockMemoizer{memoizeResult: &mockRepository{}, memoizeCached: true},
+				PackageHost:     "go.example.com",
+				MaxAge:          30 * time.Second,
+				Proxy: http.HandlerFunc(func(response http.ResponseWriter, _ *http.Request) {
+					_, _ = response.Write([]byte("proxy"))
+				}),
+			}
+
+			appContext.getMux().ServeHTTP(response, request)
+
+			if response.Body.String() != tt.wantBody {
+				t.Errorf("invalid body %q", response.Body.String())
+			}
+			if response.Header().Get("Cache-Control") != "public, max-age=30" {
+				t.Error("invalid headers")
+			}
+		})
+	}
+}
+
+type mockProxySource struct {
+	err error
+}
+
+func (m *mockProxySource) Private(_ context.Context, _ string) (bool, error) {
+	return false, nil
+}
+
+func (m *mockProxySource) Tags(_ context.Context, _ string) ([]string, error) {
+	return nil, m.err
+}
+
+func (m *mockProxySource) CommitTime(_ context.Context, _, _ string) (time.Time, error) {
+	return time.Time{}, m.err
+}
+
+func (m *mockProxySource) File(_ context.Context, _, _, _ string) ([]byte, error) {
+	return nil, m.err
+}
+
+func (m *mockProxySource) Archive(_ context.Context, _, _ string) ([]byte, error) {
+	return nil, m.err
+}
+
+func Test_appContext_handleError_proxy(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantCode       int
+		wantRetryAfter string
+	}{
+		{
+			name:     "not-found",
+			err:      repository.ErrNotFound,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:           "rate-limited",
+			err:            &repository.RetryAfterError{Err: repository.ErrRateLimited, RetryAfter: 1500 * time.Millisecond},
+			wantCode:       http.StatusTooManyRequests,
+			wantRetryAfter: "2",
+		},
+		{
+			name:     "timeout",
+			err:      repository.ErrTimeout,
+			wantCode: http.StatusGatewayTimeout,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appContext := &AppContext{Metrics: NewMetrics(false, &mockRegistry{}, &mockRegistry{})}
+			proxy := goproxy.New(&mockProxySource{err: tt.err}, &mockMemoizer{memoizeErr: tt.err}, "go.example.com", goproxy.WithErrorHandler(appContext.handleError))
+			response := httptest.NewRecorder()
+
+			proxy.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/go.example.com/foo/@v/list", nil))
+
+			if response.Code != tt.wantCode {
+				t.Errorf("invalid code %d", response.Code)
+			}
+			if got := response.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("invalid Retry-After header %q", got)
+			}
+		})
+	}
+}
+
+func Test_appContext_getMux_webhook(t *testing.T) {
+	appContext := &AppContext{
+		Metrics:         NewMetrics(false, &mockRegistry{}, &mockRegistry{}),
+		VCSHandler:      &mockVCSHandler{},
+		ResponseBuilder: &mockResponseBuilder{buildBytes: []byte("<head>")},
+		Cache:           &mockMemoizer{memoizeResult: &mockRepository{}, memoizeCached: true},
+		Webhook: http.HandlerFunc(func(response http.ResponseWriter, _ *http.Request) {
+			response.WriteHeader(http.StatusNoContent)
+		}),
+	}
+
+	response := httptest.NewRecorder()
+	appContext.getMux().ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/.internal/webhooks/github", nil))
+	if response.Code != http.StatusNoContent {
+		t.Errorf("invalid code %d for webhook request", response.Code)
+	}
+
+	response = httptest.NewRecorder()
+	appContext.getMux().ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/.internal/webhooks/github", nil))
+	if response.Code == http.StatusNoContent {
+		t.Error("webhook handles GET requests")
+	}
+}
+
 func Test_appContext_buildResponse(t *testing.T) {
 	type fields struct {
 		Metrics            *Metrics
@@ -149,7 +502,7 @@ func Test_appContext_buildResponse(t *testing.T) {
 			wantErr:     true,
 			wantCode:    http.StatusOK,
 			wantBody:    []byte(""),
-			wantHeaders: http.Header{},
+			wantHeaders: http.Header{"X-Cache": {"Miss"}},
 		},
 		{
 			name: "slash-home-page",
@@ -263,121 +616,700 @@ func Test_appContext_buildResponse(t *testing.T) {
 	}
 }
 
-func Test_appContext_handleRequest(t *testing.T) {
+func Test_appContext_buildResponse_subpath(t *testing.T) {
 	type fields struct {
-		Metrics            *Metrics
-		VCSHandler         VCSHandler
-		ResponseBuilder    ResponseBuilder
-		Cache              Memoizer
-		PackageHost        string
-		ListenAndServeAddr string
-		MaxAge             time.Duration
-	}
-	type args struct {
-		response http.ResponseWriter
-		request  *http.Request
+		VCSHandler       VCSHandler
+		ValidateSubpaths bool
 	}
 	tests := []struct {
-		name        string
-		fields      fields
-		args        args
-		wantCode    int
-		wantHeaders http.Header
-		wantBody    []byte
+		name          string
+		fields        fields
+		path          string
+		wantErr       bool
+		wantErrResult error
+		wantPrefix    string
 	}{
 		{
-			name: "ok",
-			fields: fields{
-				Metrics:         NewMetrics(false, &mockRegistry{}, &mockRegistry{}),
-				VCSHandler:      &mockVCSHandler{},
-				ResponseBuilder: &mockResponseBuilder{buildBytes: []byte("<head>")},
-				Cache:           &mockMemoizer{memoizeResult: &mockRepository{}, memoizeCached: true},
-				MaxAge:          30 * time.Second,
-			},
-			args: args{
-				response: httptest.NewRecorder(),
-				request:  httptest.NewRequest(http.MethodGet, "/foo", nil),
-			},
-			wantCode: http.StatusOK,
-			wantHeaders: http.Header{
-				"X-Cache":      {"Hit"},
-				"Content-Type": {"text/html; charset=utf-8"},
-			},
-			wantBody: []byte("<head>"),
+			name:       "repo",
+			fields:     fields{VCSHandler: &mockDirectoryCheckerVCSHandler{}, ValidateSubpaths: true},
+			path:       "/foo",
+			wantPrefix: "go.example.com/foo",
 		},
 		{
-			name: "not-found-error",
-			fields: fields{
-				Metrics:         NewMetrics(false, &mockRegistry{}, &mockRegistry{}),
-				VCSHandler:      &mockVCSHandler{},
-				ResponseBuilder: &mockResponseBuilder{},
-				Cache:           &mockMemoizer{memoizeErr: repository.ErrNotFound, memoizeCached: true},
-				MaxAge:          30 * time.Second,
-			},
-			args: args{
-				response: httptest.NewRecorder(),
-				request:  httptest.NewRequest(http.MethodGet, "/foo", nil),
-			},
-			wantCode: http.StatusNotFound,
-			wantHeaders: http.Header{
-				"Content-Type":           {"text/plain; charset=utf-8"},
-				"X-Content-Type-Options": {"nosniff"},
-			},
-			wantBody: []byte("module not found\n"),
+			name:       "sub-package-without-validation",
+			fields:     fields{VCSHandler: &mockDirectoryCheckerVCSHandler{}},
+			path:       "/foo/sub/pkg",
+			wantPrefix: "go.example.com/foo",
 		},
 		{
-			name: "bad-request",
-			fields: fields{
-				Metrics:         NewMetrics(false, &mockRegistry{}, &mockRegistry{}),
-				VCSHandler:      &mockVCSHandler{},
-				ResponseBuilder: &mockResponseBuilder{},
-				Cache:           &mockMemoizer{memoizeErr: errors.New("error"), memoizeCached: true},
-				MaxAge:          30 * time.Second,
-			},
-			args: args{
-				response: httptest.NewRecorder(),
-				request:  httptest.NewRequest(http.MethodGet, "/foo", nil),
-			},
-			wantCode: http.StatusBadRequest,
-			wantHeaders: http.Header{
-				"Content-Type":           {"text/plain; charset=utf-8"},
-				"X-Content-Type-Options": {"nosniff"},
-			},
-			wantBody: []byte("bad request\n"),
+			name:       "sub-package-without-directory-checker",
+			fields:     fields{VCSHandler: &mockVCSHandler{}, ValidateSubpaths: true},
+			path:       "/foo/sub/pkg",
+			wantPrefix: "go.example.com/foo",
+		},
+		{
+			name:       "sub-package-exists",
+			fields:     fields{VCSHandler: &mockDirectoryCheckerVCSHandler{hasDirectoryResult: true}, ValidateSubpaths: true},
+			path:       "/foo/sub/pkg",
+			wantPrefix: "go.example.com/foo",
+		},
+		{
+			name:          "sub-package-does-not-exist",
+			fields:        fields{VCSHandler: &mockDirectoryCheckerVCSHandler{}, ValidateSubpaths: true},
+			path:          "/foo/sub/pkg",
+			wantErr:       true,
+			wantErrResult: repository.ErrNotFound,
+		},
+		{
+			name:    "sub-package-error",
+			fields:  fields{VCSHandler: &mockDirectoryCheckerVCSHandler{hasDirectoryErr: errors.New("error")}, ValidateSubpaths: true},
+			path:    "/foo/sub/pkg",
+			wantErr: true,
+		},
+		{
+			name:          "invalid-path",
+			fields:        fields{VCSHandler: &mockVCSHandler{}},
+			path:          "/foo/../bar",
+			wantErr:       true,
+			wantErrResult: importpath.ErrInvalid,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			responseBuilder := &mockResponseBuilder{}
 			appContext := &AppContext{
-				Metrics:         tt.fields.Metrics,
-				VCSHandler:      tt.fields.VCSHandler,
-				ResponseBuilder: tt.fields.ResponseBuilder,
-				Cache:           tt.fields.Cache,
-				PackageHost:     tt.fields.PackageHost,
-				ServerAddr:      tt.fields.ListenAndServeAddr,
-				MaxAge:          tt.fields.MaxAge,
+				Metrics:          NewMetrics(false, &mockRegistry{}, &mockRegistry{}),
+				VCSHandler:       tt.fields.VCSHandler,
+				ResponseBuilder:  responseBuilder,
+				Cache:            &mockMemoizer{memoizeResult: &mockRepository{}},
+				PackageHost:      "go.example.com",
+				ValidateSubpaths: tt.fields.ValidateSubpaths,
 			}
-			appContext.handleRequest(tt.args.response, tt.args.request)
-			response := tt.args.response.(*httptest.ResponseRecorder)
-			if response.Code != tt.wantCode {
-				t.Error("invalid code")
+			if _, ok := tt.fields.VCSHandler.(DirectoryChecker); ok {
+				appContext.Cache = cache.NewMemoizer(cache.NewMemoryStore(0), time.Minute, 0, 0)
+				_, _, _ = appContext.Cache.Memoize("foo", func() (any, error) { return &mockRepository{}, nil })
 			}
-			if !reflect.DeepEqual(tt.args.response.Header(), tt.wantHeaders) {
-				t.Error("invalid headers")
+			err := appContext.buildResponse(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("buildResponse() error = %v, wantErr %v", err, tt.wantErr)
+				return
 			}
-			if !bytes.Equal(response.Body.Bytes(), tt.wantBody) {
-				t.Error("invalid body")
+			if tt.wantErrResult != nil && !errors.Is(err, tt.wantErrResult) {
+				t.Errorf("buildResponse() error = %v, wantErrResult %v", err, tt.wantErrResult)
+			}
+			if err == nil && responseBuilder.buildData.ImportPrefix != tt.wantPrefix {
+				t.Errorf("buildResponse() prefix = %v, want %v", responseBuilder.buildData.ImportPrefix, tt.wantPrefix)
 			}
 		})
 	}
 }
 
-func Test_appContext_handleHealth(t *testing.T) {
-	response := httptest.NewRecorder()
-	request := httptest.NewRequest(http.MethodGet, "/.internal/health", nil)
-	appContext := &AppContext{}
-	wantCode := http.StatusOK
-	wantBody := []byte("ok")
+func Test_appContext_validateSubpath_cached(t *testing.T) {
+	vcsHandler := &mockDirectoryCheckerVCSHandler{}
+	appContext := &AppContext{
+		VCSHandler:       vcsHandler,
+		Cache:            cache.NewMemoizer(cache.NewMemoryStore(0), time.Minute, 0, 0),
+		ValidateSubpaths: true,
+	}
+	for i := 0; i < 2; i++ {
+		if err := appContext.validateSubpath(context.Background(), "foo", "sub/pkg"); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("validateSubpath() error = %v", err)
+		}
+	}
+
+	if vcsHandler.hasDirectoryCalls != 1 {
+		t.Errorf("HasDirectory() called %d times, want 1", vcsHandler.hasDirectoryCalls)
+	}
+}
+
+func Test_appContext_buildResponse_nestedModule(t *testing.T) {
+	tests := []struct {
+		name             string
+		vcsHandler       VCSHandler
+		path             string
+		wantErr          bool
+		wantPrefix       string
+		wantSubdirectory string
+	}{
+		{
+			name:       "repo",
+			vcsHandler: &mockModuleListerVCSHandler{modulesResult: []string{"tools"}},
+			path:       "/foo",
+			wantPrefix: "go.example.com/foo",
+		},
+		{
+			name:             "nested-module",
+			vcsHandler:       &mockModuleListerVCSHandler{modulesResult: []string{"tools"}},
+			path:             "/foo/tools",
+			wantPrefix:       "go.example.com/foo/tools",
+			wantSubdirectory: "tools",
+		},
+		{
+			name:             "nested-module-package",
+			vcsHandler:       &mockModuleListerVCSHandler{modulesResult: []string{"api", "api/v2"}},
+			path:             "/foo/api/v2/client",
+			wantPrefix:       "go.example.com/foo/api/v2",
+			wantSubdirectory: "api/v2",
+		},
+		{
+			name:       "root-module-package",
+			vcsHandler: &mockModuleListerVCSHandler{modulesResult: []string{"tools"}},
+			path:       "/foo/toolsx",
+			wantPrefix: "go.example.com/foo",
+		},
+		{
+			name:       "no-module-lister",
+			vcsHandler: &mockVCSHandler{},
+			path:       "/foo/tools",
+			wantPrefix: "go.example.com/foo",
+		},
+		{
+			name:       "module-lister-error",
+			vcsHandler: &mockModuleListerVCSHandler{modulesErr: errors.New("error")},
+			path:       "/foo/tools",
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responseBuilder := &mockResponseBuilder{}
+			appContext := &AppContext{
+				Metrics:         NewMetrics(false, &mockRegistry{}, &mockRegistry{}),
+				VCSHandler:      tt.vcsHandler,
+				ResponseBuilder: responseBuilder,
+				Cache:           cache.NewMemoizer(cache.NewMemoryStore(0), time.Minute, 0, 0),
+				PackageHost:     "go.example.com",
+			}
+			_, _, _ = appContext.Cache.Memoize("foo", func() (any, error) { return &mockRepository{}, nil })
+			err := appContext.buildResponse(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("buildResponse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if responseBuilder.buildData.ImportPrefix != tt.wantPrefix {
+				t.Errorf("buildResponse() prefix = %v, want %v", responseBuilder.buildData.ImportPrefix, tt.wantPrefix)
+			}
+			if responseBuilder.buildData.Subdirectory != tt.wantSubdirectory {
+				t.Errorf("buildResponse() subdirectory = %v, want %v", responseBuilder.buildData.Subdirectory, tt.wantSubdirectory)
+			}
+		})
+	}
+}
+
+func Test_appContext_buildResponse_majorVersion(t *testing.T) {
+	tests := []struct {
+		name          string
+		vcsHandler    *mockMajorVersionResolverVCSHandler
+		path          string
+		wantErr       bool
+		wantErrResult error
+		wantLookup    bool
+		wantDir       string
+	}{
+		{
+			name:       "module",
+			vcsHandler: &mockMajorVersionResolverVCSHandler{majorVersionLayoutResult: repository.MajorVersionSubdirectory},
+			path:       "/foo/v2",
+		},
+		{
+			name:       "subdirectory-package",
+			vcsHandler: &mockMajorVersionResolverVCSHandler{majorVersionLayoutResult: repository.MajorVersionSubdirectory, hasDirectoryResult: true},
+			path:       "/foo/v2/pkg",
+			wantLookup: true,
+			wantDir:    "v2/pkg",
+		},
+		{
+			name:          "subdirectory-package-not-found",
+			vcsHandler:    &mockMajorVersionResolverVCSHandler{majorVersionLayoutResult: repository.MajorVersionSubdirectory},
+			path:          "/foo/v2/missing",
+			wantErr:       true,
+			wantErrResult: repository.ErrNotFound,
+		},
+		{
+			name:       "branch-package",
+			vcsHandler: &mockMajorVersionResolverVCSHandler{majorVersionLayoutResult: repository.MajorVersionBranch},
+			path:       "/foo/v2/only-on-branch",
+			wantLookup: true,
+			wantDir:    "",
+		},
+		{
+			name:       "tag-only",
+			vcsHandler: &mockMajorVersionResolverVCSHandler{majorVersionLayoutErr: repository.ErrNotFound},
+			path:       "/foo/v2",
+		},
+		{
+			name:       "tag-only-package",
+			vcsHandler: &mockMajorVersionResolverVCSHandler{majorVersionLayoutErr: repository.ErrNotFound},
+			path:       "/foo/v2/pkg",
+			wantLookup: true,
+			wantDir:    "",
+		},
+		{
+			name:       "lookup-error",
+			vcsHandler: &mockMajorVersionResolverVCSHandler{majorVersionLayoutErr: repository.ErrUnavailable},
+			path:       "/foo/v2/pkg",
+			wantLookup: true,
+			wantDir:    "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responseBuilder := &mockResponseBuilder{}
+			appContext := &AppContext{
+				Metrics:          NewMetrics(false, &mockRegistry{}, &mockRegistry{}),
+				VCSHandler:       tt.vcsHandler,
+				ResponseBuilder:  responseBuilder,
+				Cache:            cache.NewMemoizer(cache.NewMemoryStore(0), time.Minute, 0, 0),
+				PackageHost:      "go.example.com",
+				ValidateSubpaths: true,
+			}
+			_, _, _ = appContext.Cache.Memoize("foo", func() (any, error) { return &mockRepository{}, nil })
+			err := appContext.buildResponse(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("buildResponse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErrResult != nil && !errors.Is(err, tt.wantErrResult) {
+				t.Errorf("buildResponse() error = %v, wantErrResult %v", err, tt.wantErrResult)
+			}
+			if err != nil {
+				return
+			}
+			if responseBuilder.buildData.ImportPrefix != "go.example.com/foo" || responseBuilder.buildData.Subdirectory != "" {
+				t.Errorf("buildResponse() prefix = %v, subdirectory = %v", responseBuilder.buildData.ImportPrefix, responseBuilder.buildData.Subdirectory)
+			}
+			if (tt.vcsHandler.majorVersionLayoutCalls > 0) != tt.wantLookup {
+				t.Errorf("MajorVersionLayout() called %d times, want lookup %v", tt.vcsHandler.majorVersionLayoutCalls, tt.wantLookup)
+			}
+			if tt.vcsHandler.hasDirectoryDir != tt.wantDir {
+				t.Errorf("HasDirectory() dir = %v, want %v", tt.vcsHandler.hasDirectoryDir, tt.wantDir)
+			}
+		})
+	}
+}
+
+func Test_appContext_buildResponse_source(t *testing.T) {
+	vcsRepository := &mockRepository{SourceURLsResult: repository.SourceURLs{
+		Home:      "https://example.com/foo",
+		Directory: "https://example.com/foo/tree/main/tools{/dir}",
+		File:      "https://example.com/foo/blob/main/tools{/dir}/{file}#L{line}",
+	}}
+	responseBuilder := &mockResponseBuilder{}
+	appContext := &AppContext{
+		Metrics:         NewMetrics(false, &mockRegistry{}, &mockRegistry{}),
+		VCSHandler:      &mockModuleListerVCSHandler{modulesResult: []string{"tools"}},
+		ResponseBuilder: responseBuilder,
+		Cache:           cache.NewMemoizer(cache.NewMemoryStore(0), time.Minute, 0, 0),
+		PackageHost:     "go.example.com",
+	}
+	_, _, _ = appContext.Cache.Memoize("foo", func() (any, error) { return vcsRepository, nil })
+
+	if err := appContext.buildResponse(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/foo/tools/pkg", nil)); err != nil {
+		t.Fatalf("buildResponse() error = %v", err)
+	}
+
+	if vcsRepository.SourceURLsDir != "tools" {
+		t.Errorf("GetSourceURLs() dir = %v, want tools", vcsRepository.SourceURLsDir)
+	}
+	got := responseBuilder.buildData
+	if got.SourceHome != vcsRepository.SourceURLsResult.Home || got.SourceDirectory != vcsRepository.SourceURLsResult.Directory || got.SourceFile != vcsRepository.SourceURLsResult.File {
+		t.Errorf("buildResponse() source = %v, %v, %v", got.SourceHome, got.SourceDirectory, got.SourceFile)
+	}
+}
+
+func Test_findModuleDir(t *testing.T) {
+	modules := []string{"api", "api/v2", "tools"}
+	tests := []struct {
+		subpath string
+		want    string
+	}{
+		{subpath: "pkg", want: ""},
+		{subpath: "api", want: "api"},
+		{subpath: "api/client", want: "api"},
+		{subpath: "api/v2", want: "api/v2"},
+		{subpath: "api/v2/client", want: "api/v2"},
+		{subpath: "api/v20", want: "api"},
+		{subpath: "toolsx", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.subpath, func(t *testing.T) {
+			if got := findModuleDir(modules, tt.subpath); got != tt.want {
+				t.Errorf("findModuleDir() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_appContext_checkModulePath(t *testing.T) {
+	tests := []struct {
+		name            string
+		modulePathCheck string
+		vcsRepository   repository.Repository
+		moduleDir       string
+		wantErr         bool
+		wantWarning     string
+		wantMismatches  float64
+	}{
+		{
+			name:            "off",
+			modulePathCheck: modulePathCheckOff,
+			vcsRepository:   &mockModulePathRepository{modulePath: "github.com/owner/foo"},
+		},
+		{
+			name:            "nested-module",
+			modulePathCheck: modulePathCheckRefuse,
+			vcsRepository:   &mockModulePathRepository{modulePath: "github.com/owner/foo"},
+			moduleDir:       "tools",
+		},
+		{
+			name:            "no-declarer",
+			modulePathCheck: modulePathCheckRefuse,
+			vcsRepository:   &mockRepository{},
+		},
+		{
+			name:            "no-go-mod",
+			modulePathCheck: modulePathCheckRefuse,
+			vcsRepository:   &mockModulePathRepository{},
+		},
+		{
+			name:            "match",
+			modulePathCheck: modulePathCheckRefuse,
+			vcsRepository:   &mockModulePathRepository{modulePath: "go.example.com/foo"},
+		},
+		{
+			name:            "match-major-version",
+			modulePathCheck: modulePathCheckRefuse,
+			vcsRepository:   &mockModulePathRepository{modulePath: "go.example.com/foo/v2"},
+		},
+		{
+			name:            "mismatch-log",
+			modulePathCheck: modulePathCheckLog,
+			vcsRepository:   &mockModulePathRepository{modulePath: "github.com/owner/foo"},
+			wantMismatches:  1,
+		},
+		{
+			name:            "mismatch-warn",
+			modulePathCheck: modulePathCheckWarn,
+			vcsRepository:   &mockModulePathRepository{modulePath: "github.com/owner/foo"},
+			wantWarning:     `299 - "go.mod declares module path github.com/owner/foo"`,
+			wantMismatches:  1,
+		},
+		{
+			name:            "mismatch-refuse",
+			modulePathCheck: modulePathCheckRefuse,
+			vcsRepository:   &mockModulePathRepository{modulePath: "github.com/owner/foo"},
+			wantErr:         true,
+			wantMismatches:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := prometheus.NewRegistry()
+			appContext := &AppContext{
+				Metrics:         NewMetrics(false, registry, registry),
+				ModulePathCheck: tt.modulePathCheck,
+			}
+			response := httptest.NewRecorder()
+			err := appContext.checkModulePath(response, tt.vcsRepository, "foo", "go.example.com/foo", tt.moduleDir)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkModulePath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, errModulePathMismatch) {
+				t.Errorf("checkModulePath() error = %v, want %v", err, errModulePathMismatch)
+			}
+			if got := response.Header().Get("Warning"); got != tt.wantWarning {
+				t.Errorf("checkModulePath() warning = %v, want %v", got, tt.wantWarning)
+			}
+			if got := testutil.ToFloat64(appContext.Metrics.ModulePathMismatch.With(prometheus.Labels{moduleLabel: "foo"})); got != tt.wantMismatches {
+				t.Errorf("checkModulePath() mismatches = %v, want %v", got, tt.wantMismatches)
+			}
+		})
+	}
+}
+
+func Test_appContext_handleRequest_modulePathMismatch(t *testing.T) {
+	appContext := &AppContext{
+		Metrics:         NewMetrics(false, &mockRegistry{}, &mockRegistry{}),
+		VCSHandler:      &mockVCSHandler{},
+		ResponseBuilder: &mockResponseBuilder{},
+		Cache:           &mockMemoizer{memoizeResult: &mockModulePathRepository{modulePath: "github.com/owner/foo"}},
+		PackageHost:     "go.example.com",
+		ModulePathCheck: modulePathCheckRefuse,
+	}
+	response := httptest.NewRecorder()
+
+	appContext.handleRequest(response, httptest.NewRequest(http.MethodGet, "/foo", nil))
+
+	if response.Code != http.StatusNotFound {
+		t.Errorf("invalid code %d", response.Code)
+	}
+	if response.Body.String() != "module path mismatch\n" {
+		t.Errorf("invalid body %q", response.Body.String())
+	}
+}
+
+type mockSlowVCSHandler struct {
+	mockVCSHandler
+}
+
+func (m *mockSlowVCSHandler) Fetch(ctx context.Context, _ string) (repository.Repository, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func Test_newServer_backendTimeout(t *testing.T) {
+	defer func(backend, request time.Duration) {
+		backendTimeout, requestTimeout = backend, request
+	}(backendTimeout, requestTimeout)
+	backendTimeout, requestTimeout = 300*time.Millisecond, 100*time.Millisecond
+
+	appContext := &AppContext{
+		Metrics:         NewMetrics(false, &mockRegistry{}, &mockRegistry{}),
+		VCSHandler:      &mockSlowVCSHandler{},
+		ResponseBuilder: &mockResponseBuilder{},
+		Cache:           cache.NewMemoizer(cache.NewMemoryStore(0), time.Minute, 0, 0),
+		PackageHost:     "go.example.com",
+	}
+	server := httptest.NewUnstartedServer(nil)
+	server.Config = newServer("", appContext.getMux())
+	server.Start()
+	defer server.Close()
+
+	response, err := server.Client().Get(server.URL + "/foo")
+	if err != nil {
+		t.Fatalf("response didn't reach the client: %s", err)
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusGatewayTimeout || string(body) != "gateway timeout\n" {
+		t.Errorf("invalid response %d %q", response.StatusCode, body)
+	}
+}
+
+func Test_appContext_handleRequest(t *testing.T) {
+	type fields struct {
+		Metrics            *Metrics
+		VCSHandler         VCSHandler
+		ResponseBuilder    ResponseBuilder
+		Cache              Memoizer
+		PackageHost        string
+		ListenAndServeAddr string
+		MaxAge             time.Duration
+	}
+	type args struct {
+		response http.ResponseWriter
+		request  *http.Request
+	}
+	tests := []struct {
+		name        string
+		fields      fields
+		args        args
+		wantCode    int
+		wantHeaders http.Header
+		wantBody    []byte
+	}{
+		{
+			name: "ok",
+			fields: fields{
+				Metrics:         NewMetrics(false, &mockRegistry{}, &mockRegistry{}),
+				VCSHandler:      &mockVCSHandler{},
+				ResponseBuilder: &mockResponseBuilder{buildBytes: []byte("<head>")},
+				Cache:           &mockMemoizer{memoizeResult: &mockRepository{}, memoizeCached: true},
+				MaxAge:          30 * time.Second,
+			},
+			args: args{
+				response: httptest.NewRecorder(),
+				request:  httptest.NewRequest(http.MethodGet, "/foo", nil),
+			},
+			wantCode: http.StatusOK,
+			wantHeaders: http.Header{
+				"X-Cache":      {"Hit"},
+				"Content-Type": {"text/html; charset=utf-8"},
+			},
+			wantBody: []byte("<head>"),
+		},
+		{
+			name: "not-found-error",
+			fields: fields{
+				Metrics:         NewMetrics(false, &mockRegistry{}, &mockRegistry{}),
+				VCSHandler:      &mockVCSHandler{},
+				ResponseBuilder: &mockResponseBuilder{},
+				Cache:           &mockMemoizer{memoizeErr: repository.ErrNotFound, memoizeCached: true},
+				MaxAge:          30 * time.Second,
+			},
+			args: args{
+				response: httptest.NewRecorder(),
+				request:  httptest.NewRequest(http.MethodGet, "/foo", nil),
+			},
+			wantCode: http.StatusNotFound,
+			wantHeaders: http.Header{
+				"X-Cache":                {"Negative-Hit"},
+				"Content-Type":           {"text/plain; charset=utf-8"},
+				"X-Content-Type-Options": {"nosniff"},
+			},
+			wantBody: []byte("module not found\n"),
+		},
+		{
+			name: "bad-request",
+			fields: fields{
+				Metrics:         NewMetrics(false, &mockRegistry{}, &mockRegistry{}),
+				VCSHandler:      &mockVCSHandler{},
+				ResponseBuilder: &mockResponseBuilder{},
+				Cache:           &mockMemoizer{memoizeErr: importpath.ErrInvalid, memoizeCached: true},
+				MaxAge:          30 * time.Second,
+			},
+			args: args{
+				response: httptest.NewRecorder(),
+				request:  httptest.NewRequest(http.MethodGet, "/foo", nil),
+			},
+			wantCode: http.StatusBadRequest,
+			wantHeaders: http.Header{
+				"Content-Type":           {"text/plain; charset=utf-8"},
+				"X-Content-Type-Options": {"nosniff"},
+			},
+			wantBody: []byte("bad request\n"),
+		},
+		{
+			name: "forbidden",
+			fields: fields{
+				Metrics:         NewMetrics(false, &mockRegistry{}, &mockRegistry{}),
+				VCSHandler:      &mockVCSHandler{},
+				ResponseBuilder: &mockResponseBuilder{},
+				Cache:           &mockMemoizer{memoizeErr: fmt.Errorf("%w: error", repository.ErrForbidden), memoizeCached: true},
+				MaxAge:          30 * time.Second,
+			},
+			args: args{
+				response: httptest.NewRecorder(),
+				request:  httptest.NewRequest(http.MethodGet, "/foo", nil),
+			},
+			wantCode: http.StatusForbidden,
+			wantHeaders: http.Header{
+				"Content-Type":           {"text/plain; charset=utf-8"},
+				"X-Content-Type-Options": {"nosniff"},
+			},
+			wantBody: []byte("forbidden\n"),
+		},
+		{
+			name: "rate-limited",
+			fields: fields{
+				Metrics:         NewMetrics(false, &mockRegistry{}, &mockRegistry{}),
+				VCSHandler:      &mockVCSHandler{},
+				ResponseBuilder: &mockResponseBuilder{},
+				Cache:           &mockMemoizer{memoizeErr: &repository.RetryAfterError{Err: repository.ErrRateLimited, RetryAfter: 1500 * time.Millisecond}, memoizeCached: true},
+				MaxAge:          30 * time.Second,
+			},
+			args: args{
+				response: httptest.NewRecorder(),
+				request:  httptest.NewRequest(http.MethodGet, "/foo", nil),
+			},
+			wantCode: http.StatusTooManyRequests,
+			wantHeaders: http.Header{
+				"Content-Type":           {"text/plain; charset=utf-8"},
+				"Retry-After":            {"2"},
+				"X-Content-Type-Options": {"nosniff"},
+			},
+			wantBody: []byte("too many requests\n"),
+		},
+		{
+			name: "unavailable",
+			fields: fields{
+				Metrics:         NewMetrics(false, &mockRegistry{}, &mockRegistry{}),
+				VCSHandler:      &mockVCSHandler{},
+				ResponseBuilder: &mockResponseBuilder{},
+				Cache:           &mockMemoizer{memoizeErr: repository.ErrUnavailable, memoizeCached: true},
+				MaxAge:          30 * time.Second,
+			},
+			args: args{
+				response: httptest.NewRecorder(),
+				request:  httptest.NewRequest(http.MethodGet, "/foo", nil),
+			},
+			wantCode: http.StatusServiceUnavailable,
+			wantHeaders: http.Header{
+				"Content-Type":           {"text/plain; charset=utf-8"},
+				"X-Content-Type-Options": {"nosniff"},
+			},
+			wantBody: []byte("service unavailable\n"),
+		},
+		{
+			name: "timeout",
+			fields: fields{
+				Metrics:         NewMetrics(false, &mockRegistry{}, &mockRegistry{}),
+				VCSHandler:      &mockVCSHandler{},
+				ResponseBuilder: &mockResponseBuilder{},
+				Cache:           &mockMemoizer{memoizeErr: repository.ErrTimeout, memoizeCached: true},
+				MaxAge:          30 * time.Second,
+			},
+			args: args{
+				response: httptest.NewRecorder(),
+				request:  httptest.NewRequest(http.MethodGet, "/foo", nil),
+			},
+			wantCode: http.StatusGatewayTimeout,
+			wantHeaders: http.Header{
+				"Content-Type":           {"text/plain; charset=utf-8"},
+				"X-Content-Type-Options": {"nosniff"},
+			},
+			wantBody: []byte("gateway timeout\n"),
+		},
+		{
+			name: "bad-gateway",
+			fields: fields{
+				Metrics:         NewMetrics(false, &mockRegistry{}, &mockRegistry{}),
+				VCSHandler:      &mockVCSHandler{},
+				ResponseBuilder: &mockResponseBuilder{},
+				Cache:           &mockMemoizer{memoizeErr: errors.New("error"), memoizeCached: true},
+				MaxAge:          30 * time.Second,
+			},
+			args: args{
+				response: httptest.NewRecorder(),
+				request:  httptest.NewRequest(http.MethodGet, "/foo", nil),
+			},
+			wantCode: http.StatusBadGateway,
+			wantHeaders: http.Header{
+				"Content-Type":           {"text/plain; charset=utf-8"},
+				"X-Content-Type-Options": {"nosniff"},
+			},
+			wantBody: []byte("bad gateway\n"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appContext := &AppContext{
+				Metrics:         tt.fields.Metrics,
+				VCSHandler:      tt.fields.VCSHandler,
+				ResponseBuilder: tt.fields.ResponseBuilder,
+				Cache:           tt.fields.Cache,
+				PackageHost:     tt.fields.PackageHost,
+				ServerAddr:      tt.fields.ListenAndServeAddr,
+				MaxAge:          tt.fields.MaxAge,
+			}
+			appContext.handleRequest(tt.args.response, tt.args.request)
+			response := tt.args.response.(*httptest.ResponseRecorder)
+			if response.Code != tt.wantCode {
+				t.Error("invalid code")
+			}
+			if !reflect.DeepEqual(tt.args.response.Header(), tt.wantHeaders) {
+				t.Error("invalid headers")
+			}
+			if !bytes.Equal(response.Body.Bytes(), tt.wantBody) {
+				t.Error("invalid body")
+			}
+		})
+	}
+}
+
+func Test_appContext_handleHealth(t *testing.T) {
+	response := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/.internal/health", nil)
+	appContext := &AppContext{}
+	wantCode := http.StatusOK
+	wantBody := []byte("ok")
 
 	appContext.getMux().ServeHTTP(response, request)
 
@@ -402,39 +1334,367 @@ func Test_handleCacheControlHeader(t *testing.T) {
 	}
 }
 
-func Test_handleXCacheHeader(t *testing.T) {
+func Test_appContext_handleXCacheHeader(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      cache.Status
+		want        string
+		wantWarning string
+		wantResult  string
+	}{
+		{
+			name:       "miss",
+			status:     cache.StatusMiss,
+			want:       "Miss",
+			wantResult: "miss",
+		},
+		{
+			name:       "hit",
+			status:     cache.StatusHit,
+			want:       "Hit",
+			wantResult: "hit",
+		},
+		{
+			name:       "negative-hit",
+			status:     cache.StatusNegativeHit,
+			want:       "Negative-Hit",
+			wantResult: "negative_hit",
+		},
+		{
+			name:        "stale",
+			status:      cache.StatusStale,
+			want:        "Stale",
+			wantWarning: `110 - "Response is Stale"`,
+			wantResult:  "stale",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appContext := &AppContext{Metrics: NewMetrics(false, &mockRegistry{}, &mockRegistry{})}
+			response := httptest.NewRecorder()
+
+			appContext.handleXCacheHeader(response, tt.status)
+
+			if response.Header().Get("X-Cache") != tt.want {
+				t.Error("wrong header value")
+			}
+			if response.Header().Get("Warning") != tt.wantWarning {
+				t.Error("wrong warning header value")
+			}
+			if got := testutil.ToFloat64(appContext.Metrics.CacheResults.With(prometheus.Labels{cacheResultLabel: tt.wantResult})); got != 1 {
+				t.Errorf("cache results = %v, want 1", got)
+			}
+		})
+	}
+}
+
+func Test_appContext_memoize(t *testing.T) {
+	tests := []struct {
+		name  string
+		cache Memoizer
+		want  cache.Status
+	}{
+		{
+			name:  "miss",
+			cache: &mockMemoizer{memoizeResult: &mockRepository{}},
+			want:  cache.StatusMiss,
+		},
+		{
+			name:  "hit",
+			cache: &mockMemoizer{memoizeResult: &mockRepository{}, memoizeCached: true},
+			want:  cache.StatusHit,
+		},
+		{
+			name:  "negative-hit",
+			cache: &mockMemoizer{memoizeErr: repository.ErrNotFound, memoizeCached: true},
+			want:  cache.StatusNegativeHit,
+		},
+		{
+			name:  "status-memoizer",
+			cache: cache.NewMemoizer(cache.NewMemoryStore(0), time.Minute, 0, 0),
+			want:  cache.StatusMiss,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appContext := &AppContext{Cache: tt.cache}
+
+			if _, _, got := appContext.memoize("foo", func() (any, error) { return &mockRepository{}, nil }); got != tt.want {
+				t.Errorf("memoize() status = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_appContext_fetchRepository_revalidate(t *testing.T) {
+	first := &mockRepository{RepoRootResult: "first"}
+	vcsHandler := &mockRevalidatorVCSHandler{mockVCSHandler: mockVCSHandler{fetchResult: first}}
+	appContext := &AppContext{
+		VCSHandler: vcsHandler,
+		Cache:      cache.NewMemoizer(cache.NewMemoryStore(0), 0, 0, 0, cache.WithRevalidation(time.Hour)),
+	}
+
+	for i := 0; i < 2; i++ {
+		got, err, status := appContext.fetchRepository(context.Background(), "foo")
+		if got != first || err != nil || status != cache.StatusMiss {
+			t.Errorf("fetchRepository() = %v, %v, %v", got, err, status)
+		}
+	}
+
+	if want := []repository.Repository{nil, first}; !reflect.DeepEqual(vcsHandler.revalidatePrevious, want) {
+		t.Errorf("Revalidate() previous = %v, want %v", vcsHandler.revalidatePrevious, want)
+	}
+	if vcsHandler.fetchCalls != 0 {
+		t.Errorf("Fetch() called %d times", vcsHandler.fetchCalls)
+	}
+}
+
+func Test_appContext_resilient(t *testing.T) {
+	tests := []struct {
+		name      string
+		retrier   *resilience.Retrier
+		breaker   *resilience.CircuitBreaker
+		wantCalls int
+		wantErr   error
+	}{
+		{
+			name:      "plain",
+			wantCalls: 1,
+			wantErr:   repository.ErrUnavailable,
+		},
+		{
+			name:      "retries",
+			retrier:   resilience.NewRetrier(3, time.Millisecond, time.Millisecond),
+			wantCalls: 3,
+			wantErr:   repository.ErrUnavailable,
+		},
+		{
+			name:      "circuit-breaker",
+			retrier:   resilience.NewRetrier(3, time.Millisecond, time.Millisecond),
+			breaker:   resilience.NewCircuitBreaker(2, time.Minute),
+			wantCalls: 2,
+			wantErr:   resilience.ErrCircuitOpen,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appContext := &AppContext{Retrier: tt.retrier, Breaker: tt.breaker}
+			calls := 0
+
+			_, err := appContext.resilient(func(_ context.Context) (any, error) {
+				calls++
+				return nil, repository.ErrUnavailable
+			})(context.Background())
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("resilient() error = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("resilient() calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func Test_appContext_buildResponse_staleCache(t *testing.T) {
+	store := cache.NewMemoryStore(0)
+	appContext := &AppContext{
+		Metrics:         NewMetrics(false, &mockRegistry{}, &mockRegistry{}),
+		VCSHandler:      &mockVCSHandler{fetchErr: errors.New("error")},
+		ResponseBuilder: &mockResponseBuilder{buildBytes: []byte("<head>")},
+		Cache:           cache.NewMemoizer(store, 0, 0, time.Hour),
+	}
+	_, _, _ = appContext.Cache.Memoize("foo", func() (any, error) { return &mockRepository{}, nil })
+
+	response := httptest.NewRecorder()
+	appContext.handleRequest(response, httptest.NewRequest(http.MethodGet, "/foo", nil))
+
+	if response.Code != http.StatusOK {
+		t.Errorf("invalid code %d", response.Code)
+	}
+	if got := response.Header().Get("X-Cache"); got != "Stale" {
+		t.Errorf("X-Cache = %v, want Stale", got)
+	}
+	if got := response.Header().Get("Warning"); got == "" {
+		t.Error("no warning header")
+	}
+}
+
+func Test_appContext_buildResponse_negativeCache(t *testing.T) {
+	vcsHandler := &mockVCSHandler{fetchErr: repository.ErrNotFound}
+	appContext := &AppContext{
+		Metrics:         NewMetrics(false, &mockRegistry{}, &mockRegistry{}),
+		VCSHandler:      vcsHandler,
+		ResponseBuilder: &mockResponseBuilder{},
+		Cache:           cache.NewMemoizer(cache.NewMemoryStore(0), time.Minute, time.Minute, 0),
+	}
+
+	for _, want := range []string{"Miss", "Negative-Hit"} {
+		response := httptest.NewRecorder()
+		appContext.handleRequest(response, httptest.NewRequest(http.MethodGet, "/foo", nil))
+
+		if response.Code != http.StatusNotFound {
+			t.Errorf("invalid code %d", response.Code)
+		}
+		if got := response.Header().Get("X-Cache"); got != want {
+			t.Errorf("X-Cache = %v, want %v", got, want)
+		}
+	}
+
+	if vcsHandler.fetchCalls != 1 {
+		t.Errorf("Fetch() called %d times", vcsHandler.fetchCalls)
+	}
+}
+
+func Test_resolveSecret(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("file-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MASQUERADE_TEST_TOKEN", "env-token")
 	type args struct {
-		response http.ResponseWriter
-		cached   bool
+		value  string
+		envKey string
+		file   string
 	}
 	tests := []struct {
-		name string
-		args args
-		want string
+		name    string
+		args    args
+		want    string
+		wantErr bool
 	}{
 		{
-			name: "miss",
-			args: args{
-				response: httptest.NewRecorder(),
-				cached:   false,
-			},
-			want: "Miss",
+			name: "value",
+			args: args{value: "flag-token", envKey: "MASQUERADE_TEST_TOKEN", file: tokenFile},
+			want: "flag-token",
 		},
 		{
-			name: "hit",
-			args: args{
-				response: httptest.NewRecorder(),
-				cached:   true,
-			},
-			want: "Hit",
+			name: "file",
+			args: args{envKey: "MASQUERADE_TEST_TOKEN", file: tokenFile},
+			want: "file-token",
+		},
+		{
+			name: "env",
+			args: args{envKey: "MASQUERADE_TEST_TOKEN"},
+			want: "env-token",
+		},
+		{
+			name: "none",
+			args: args{envKey: "MASQUERADE_TEST_UNSET"},
+			want: "",
+		},
+		{
+			name:    "missing-file",
+			args:    args{file: filepath.Join(t.TempDir(), "missing")},
+			wantErr: true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			handleXCacheHeader(tt.args.response, tt.args.cached)
+			got, err := resolveSecret(tt.args.value, tt.args.envKey, tt.args.file)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("resolveSecret() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("resolveSecret() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
 
-			if tt.args.response.Header().Get("X-Cache") != tt.want {
-				t.Error("wrong header value")
+func Test_newGitHubTransport(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "")
+	type args struct {
+		token          string
+		appID          int64
+		installationID int64
+		privateKeyFile string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "anonymous",
+			want: "*http.Transport",
+		},
+		{
+			name: "token",
+			args: args{token: "the-token"},
+			want: "*github.TokenTransport",
+		},
+		{
+			name:    "app-missing-installation-id",
+			args:    args{appID: 1, privateKeyFile: "the-file"},
+			wantErr: true,
+		},
+		{
+			name:    "app-missing-private-key-file",
+			args:    args{appID: 1, installationID: 2, privateKeyFile: filepath.Join(t.TempDir(), "missing")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newGitHubTransport("https://api.github.com/", tt.args.token, "", tt.args.appID, tt.args.installationID, tt.args.privateKeyFile)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newGitHubTransport() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && reflect.TypeOf(got).String() != tt.want {
+				t.Errorf("newGitHubTransport() got = %T, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_newResponseBuilder(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.tmpl")
+	invalid := filepath.Join(dir, "invalid.tmpl")
+
+	if err := os.WriteFile(valid, []byte(`{{.ImportPrefix}} {{.VCS}} {{.RepoRoot}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(invalid, []byte(`{{.ImportPrefix`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		wantBody string
+		wantErr  bool
+	}{
+		{name: "default", wantBody: `<meta name="go-import" content="go.example.com/repo git https://example.com/repo">`},
+		{name: "custom", file: valid, wantBody: "go.example.com/repo git https://example.com/repo"},
+		{name: "invalid", file: invalid, wantErr: true},
+		{name: "missing", file: filepath.Join(dir, "missing.tmpl"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responseBuilder, err := newResponseBuilder(&TemplatesConfig{GoGet: tt.file})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("wrong error: %v", err)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			body := &bytes.Buffer{}
+			data := &goget.TemplateData{ImportPrefix: "go.example.com/repo", VCS: "git", RepoRoot: "https://example.com/repo"}
+
+			if err := responseBuilder.Build(body, data); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if !bytes.Contains(body.Bytes(), []byte(tt.wantBody)) {
+				t.Errorf("wrong body: %s", body)
 			}
 		})
 	}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.eigsys.de/masquerade/pkg/repository"
+	"log"
+	"net/http"
+	"time"
+)
+
+type RepositoryLister interface {
+	FetchAll(ctx context.Context) (map[string]repository.Repository, error)
+}
+
+type CacheWarmer interface {
+	Set(key string, value any)
+}
+
+func (a *AppContext) prewarm(ctx context.Context, repositoryLister RepositoryLister) error {
+	cacheWarmer, ok := a.Cache.(CacheWarmer)
+	if !ok {
+		return errors.New("cache doesn't support prewarming")
+	}
+
+	repositories, err := repositoryLister.FetchAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	for repo, r := range repositories {
+		cacheWarmer.Set(repo, r)
+	}
+
+	log.Printf("prewarmed the cache with %d repositories", len(repositories))
+
+	return nil
+}
+
+// startPrewarming prewarms the cache with the current VCS backend, and stops prewarming with the previous one.
+func (a *AppContext) startPrewarming(interval time.Duration) error {
+	current := a.current()
+
+	repositoryLister, ok := current.VCSHandler.(RepositoryLister)
+	if !ok {
+		return fmt.Errorf("VCS backend %q does not support prewarming", current.VCSHandler.Type())
+	}
+
+	if a.stopPrewarming != nil {
+		a.stopPrewarming()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.stopPrewarming = cancel
+
+	go a.runPrewarming(ctx, current, repositoryLister, interval)
+
+	return nil
+}
+
+// runPrewarming prewarms the cache of current, which is kept even if the settings are reloaded in the meantime, so
+// that the repositories are stored for the VCS backend that listed them.
+func (a *AppContext) runPrewarming(ctx context.Context, current *AppContext, repositoryLister RepositoryLister, interval time.Duration) {
+	if err := current.prewarm(ctx, repositoryLister); err != nil {
+		log.Printf("prewarming: %s", err)
+	}
+
+	// Readiness doesn't depend on the result, because the cache is also filled on demand.
+	a.ready.Store(true)
+
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := current.prewarm(ctx, repositoryLister); err != nil {
+				log.Printf("prewarming: %s", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (a *AppContext) handleReady(response http.ResponseWriter, _ *http.Request) {
+	if !a.ready.Load() {
+		http.Error(response, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+
+	_, _ = fmt.Fprint(response, "ok")
+}
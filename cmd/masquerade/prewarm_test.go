@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"go.eigsys.de/masquerade/pkg/cache"
+	"go.eigsys.de/masquerade/pkg/repository"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type mockRepositoryLister struct {
+	fetchAllResult map[string]repository.Repository
+	fetchAllErr    error
+	fetchAllCalls  atomic.Int32
+}
+
+func (m *mockRepositoryLister) FetchAll(_ context.Context) (map[string]repository.Repository, error) {
+	m.fetchAllCalls.Add(1)
+	return m.fetchAllResult, m.fetchAllErr
+}
+
+func Test_appContext_prewarm(t *testing.T) {
+	tests := []struct {
+		name             string
+		cache            Memoizer
+		repositoryLister *mockRepositoryLister
+		wantErr          bool
+		wantCached       []string
+	}{
+		{
+			name:             "ok",
+			cache:            cache.NewMemoizer(cache.NewMemoryStore(0), time.Hour, 0, 0),
+			repositoryLister: &mockRepositoryLister{fetchAllResult: map[string]repository.Repository{"foo": &mockRepository{}, "bar": &mockRepository{}}},
+			wantCached:       []string{"foo", "bar"},
+		},
+		{
+			name:             "lister-error",
+			cache:            cache.NewMemoizer(cache.NewMemoryStore(0), time.Hour, 0, 0),
+			repositoryLister: &mockRepositoryLister{fetchAllErr: errors.New("error")},
+			wantErr:          true,
+		},
+		{
+			name:             "unsupported-cache",
+			cache:            &mockMemoizer{},
+			repositoryLister: &mockRepositoryLister{},
+			wantErr:          true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appContext := &AppContext{Cache: tt.cache}
+
+			if err := appContext.prewarm(context.Background(), tt.repositoryLister); (err != nil) != tt.wantErr {
+				t.Errorf("prewarm() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			for _, repo := range tt.wantCached {
+				if _, _, cached := appContext.Cache.Memoize(repo, func() (any, error) { return nil, errors.New("unexpected call") }); !cached {
+					t.Errorf("%q has not been cached", repo)
+				}
+			}
+		})
+	}
+}
+
+func Test_appContext_runPrewarming(t *testing.T) {
+	appContext := &AppContext{Cache: cache.NewMemoizer(cache.NewMemoryStore(0), time.Hour, 0, 0)}
+	repositoryLister := &mockRepositoryLister{fetchAllErr: errors.New("error")}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		appContext.runPrewarming(ctx, appContext, repositoryLister, time.Millisecond)
+		close(done)
+	}()
+
+	for i := 0; i < 100 && repositoryLister.fetchAllCalls.Load() < 3; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if !appContext.ready.Load() {
+		t.Error("not ready after prewarming")
+	}
+	if repositoryLister.fetchAllCalls.Load() < 3 {
+		t.Errorf("prewarming ran %d times", repositoryLister.fetchAllCalls.Load())
+	}
+}
+
+func Test_appContext_runPrewarming_once(t *testing.T) {
+	appContext := &AppContext{Cache: cache.NewMemoizer(cache.NewMemoryStore(0), time.Hour, 0, 0)}
+	repositoryLister := &mockRepositoryLister{}
+
+	appContext.runPrewarming(context.Background(), appContext, repositoryLister, 0)
+
+	if !appContext.ready.Load() || repositoryLister.fetchAllCalls.Load() != 1 {
+		t.Errorf("ready = %v, prewarming ran %d times", appContext.ready.Load(), repositoryLister.fetchAllCalls.Load())
+	}
+}
+
+func Test_appContext_handleReady(t *testing.T) {
+	appContext := &AppContext{}
+
+	response := httptest.NewRecorder()
+	appContext.getMux().ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/.internal/ready", nil))
+	if response.Code != http.StatusServiceUnavailable {
+		t.Errorf("invalid code %d before prewarming", response.Code)
+	}
+
+	appContext.ready.Store(true)
+
+	response = httptest.NewRecorder()
+	appContext.getMux().ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/.internal/ready", nil))
+	if response.Code != http.StatusOK || response.Body.String() != "ok" {
+		t.Errorf("invalid response %d %q after prewarming", response.Code, response.Body.String())
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go.eigsys.de/masquerade/pkg/cache"
+	"go.eigsys.de/masquerade/pkg/github"
+	"go.eigsys.de/masquerade/pkg/goproxy"
+	"log"
+	"net/http"
+	"time"
+)
+
+type TTLSetter interface {
+	SetTTL(ttl, negativeTTL, maxStale time.Duration)
+}
+
+type CacheNamespacer interface {
+	Namespace(name string) *cache.Namespace
+}
+
+// current returns the AppContext with the most recently loaded settings.
+func (a *AppContext) current() *AppContext {
+	if reloaded := a.reloaded.Load(); reloaded != nil {
+		return reloaded
+	}
+
+	return a
+}
+
+// serveCurrent serves each request with the AppContext that's current when the request arrives, so that requests
+// in flight finish with the settings they started with.
+func (a *AppContext) serveCurrent(handler func(c *AppContext) http.HandlerFunc) http.HandlerFunc {
+	return func(response http.ResponseWriter, request *http.Request) {
+		handler(a.current())(response, request)
+	}
+}
+
+// withConfig returns an AppContext with the reloadable settings of config, which shares everything else with a.
+func (a *AppContext) withConfig(config *Config) (*AppContext, error) {
+	vcsHandler, err := a.vcsHandler(&config.Backend)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := vcsHandler.(DirectoryChecker); config.Backend.ValidateSubpaths && !ok {
+		return nil, fmt.Errorf("VCS backend %q does not support sub-package validation", config.Backend.Type)
+	}
+
+	if _, ok := vcsHandler.(RepositoryLister); config.Cache.Prewarm && !ok {
+		return nil, fmt.Errorf("VCS backend %q does not support prewarming", config.Backend.Type)
+	}
+
+	responseBuilder, err := newResponseBuilder(&config.Templates)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each VCS backend has its own cache entries, so that requests in flight with the previous one can't store its
+	// repositories for the next one.
+	memoizer := a.Cache
+	if namespacer, ok := a.Cache.(CacheNamespacer); ok {
+		memoizer = namespacer.Namespace(config.Backend.source())
+	}
+
+	var proxy http.Handler
+
+	if config.Listener.EnableProxy {
+		source, ok := vcsHandler.(goproxy.Source)
+		if !ok {
+			return nil, fmt.Errorf("VCS backend %q does not support the GOPROXY protocol", config.Backend.Type)
+		}
+
+		proxy = goproxy.New(source, memoizer, a.PackageHost, goproxy.WithErrorHandler(a.handleError))
+	}
+
+	var webhook http.Handler
+
+	if config.Backend.GitHub.WebhookSecretFile != "" {
+		secret, err := resolveSecret("", "", config.Backend.GitHub.WebhookSecretFile)
+		if err != nil {
+			return nil, err
+		}
+
+		invalidator, ok := memoizer.(github.Invalidator)
+		if secret == "" || !ok {
+			return nil, errors.New("the GitHub webhook requires a secret and a cache supporting invalidation")
+		}
+
+		webhook = github.NewWebhookHandler([]byte(secret), config.Backend.GitHub.Owner, invalidator)
+	}
+
+	return &AppContext{
+		Metrics:          a.Metrics,
+		VCSHandler:       vcsHandler,
+		ResponseBuilder:  responseBuilder,
+		Cache:            memoizer,
+		PackageHost:      a.PackageHost,
+		ServerAddr:       a.ServerAddr,
+		MaxAge:           config.Cache.TTL,
+		HomePageURL:      config.Listener.HomePageURL,
+		ValidateSubpaths: config.Backend.ValidateSubpaths,
+		ModulePathCheck:  config.Backend.ModulePathCheck,
+		Proxy:            proxy,
+		Webhook:          webhook,
+		AdminAddr:        a.AdminAddr,
+		AdminToken:       a.AdminToken,
+		Retrier:          a.Retrier,
+		Breaker:          a.Breaker,
+		LoadConfig:       a.LoadConfig,
+		config:           config,
+	}, nil
+}
+
+// vcsHandler returns the current VCS handler if its settings are unchanged, so that its rate limit state (including a
+// backoff after a secondary rate limit) and its conditional request validators survive a reload. Otherwise, it returns
+// a new one.
+func (a *AppContext) vcsHandler(config *BackendConfig) (VCSHandler, error) {
+	if current := a.current(); current.config != nil && current.config.Backend == *config {
+		return current.VCSHandler, nil
+	}
+
+	return newVCSHandler(config)
+}
+
+// Reload loads the configuration again and swaps the settings that can be changed without a restart (see
+// Config.withReloadable). An invalid configuration is rejected as a whole, keeping the current settings.
+func (a *AppContext) Reload() error {
+	if a.LoadConfig == nil {
+		return errors.New("no configuration to reload")
+	}
+
+	config, err := a.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	current := a.current()
+	merged := current.config.withReloadable(config)
+
+	if err := merged.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	// Even an unchanged configuration is applied, because it rereads the webhook secret and templates from their files.
+	next, err := a.withConfig(merged)
+	if err != nil {
+		return err
+	}
+
+	changes := diffConfig(current.config, config)
+	if len(changes) == 0 {
+		log.Print("config: no settings changed")
+	}
+
+	for _, change := range changes {
+		log.Printf("config: %s", change)
+	}
+
+	for _, change := range diffConfig(merged, config) {
+		log.Printf("config: %s requires a restart", change.key)
+	}
+
+	if ttlSetter, ok := a.Cache.(TTLSetter); ok {
+		ttlSetter.SetTTL(merged.Cache.TTL, merged.Cache.NegativeTTL, merged.Cache.MaxStale)
+	}
+
+	a.reloaded.Store(next)
+
+	if merged.Backend.source() != current.config.Backend.source() {
+		a.resetCache(current, merged.Cache)
+	}
+
+	return nil
+}
+
+// resetCache purges the repositories of the previous VCS backend, which are no longer served, and prewarms the cache
+// again.
+func (a *AppContext) resetCache(previous *AppContext, config CacheConfig) {
+	if cacheAdmin, ok := previous.Cache.(CacheAdmin); ok {
+		if err := cacheAdmin.Clear(); err != nil {
+			log.Printf("config: purging the cache: %s", err)
+		} else {
+			log.Print("config: purged the cache of the previous VCS backend")
+		}
+	}
+
+	if !config.Prewarm {
+		return
+	}
+
+	if err := a.startPrewarming(config.PrewarmInterval); err != nil {
+		log.Printf("config: %s", err)
+	}
+}
+
+// RateLimitRemaining reports the rate limit of the current VCS backend, if it's known.
+func (a *AppContext) RateLimitRemaining() (int, bool) {
+	reporter, ok := a.current().VCSHandler.(RateLimitReporter)
+	if !ok {
+		return 0, false
+	}
+
+	return reporter.RateLimitRemaining()
+}
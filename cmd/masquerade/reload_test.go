@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"go.eigsys.de/masquerade/pkg/cache"
+	"go.eigsys.de/masquerade/pkg/goget"
+	"go.eigsys.de/masquerade/pkg/repository"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type blockingResponseBuilder struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingResponseBuilder) Build(writer io.Writer, _ *goget.TemplateData) error {
+	close(b.started)
+	<-b.release
+	_, err := writer.Write([]byte("previous"))
+	return err
+}
+
+type mockBlockingVCSHandler struct {
+	mockVCSHandler
+	started chan struct{}
+	release chan struct{}
+}
+
+func (m *mockBlockingVCSHandler) Fetch(_ context.Context, _ string) (repository.Repository, error) {
+	close(m.started)
+	<-m.release
+	return m.fetchResult, m.fetchErr
+}
+
+func newReloadTestConfig() *Config {
+	config := defaultConfig()
+	config.Listener.PackageHost = "go.example.com"
+	config.Backend.GitHub.Owner = "the-owner"
+
+	return config
+}
+
+func newReloadTestAppContext(t *testing.T, config *Config) (*AppContext, *cache.Memoizer) {
+	t.Helper()
+
+	memoizer := cache.NewMemoizer(cache.NewMemoryStore(0), config.Cache.TTL, config.Cache.NegativeTTL, config.Cache.MaxStale)
+	base := &AppContext{
+		Metrics:     NewMetrics(false, &mockRegistry{}, &mockRegistry{}),
+		Cache:       memoizer,
+		PackageHost: config.Listener.PackageHost,
+		ServerAddr:  config.Listener.Addr,
+	}
+
+	appContext, err := base.withConfig(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	return appContext, memoizer
+}
+
+func Test_appContext_Reload(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secretFile, []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		initial func(config *Config)
+		modify  func(config *Config)
+		loadErr error
+		wantErr bool
+		check   func(t *testing.T, current *AppContext, previous *cache.Namespace)
+	}{
+		{
+			name: "reloadable settings",
+			modify: func(config *Config) {
+				config.Listener.HomePageURL = "https://www.example.com"
+				config.Cache.TTL = 2 * time.Hour
+				config.Backend.ModulePathCheck = modulePathCheckWarn
+			},
+			check: func(t *testing.T, current *AppContext, previous *cache.Namespace) {
+				if current.HomePageURL != "https://www.example.com" || current.MaxAge != 2*time.Hour || current.ModulePathCheck != modulePathCheckWarn {
+					t.Errorf("settings not reloaded: %q, %s, %q", current.HomePageURL, current.MaxAge, current.ModulePathCheck)
+				}
+
+				if entries, _ := current.Cache.(CacheAdmin).Entries(); len(entries) != 1 {
+					t.Errorf("cache purged, although the backend didn't change: %v", entries)
+				}
+			},
+		},
+		{
+			name: "new owner",
+			modify: func(config *Config) {
+				config.Backend.GitHub.Owner = "new-owner"
+			},
+			check: func(t *testing.T, current *AppContext, previous *cache.Namespace) {
+				if current.config.Backend.GitHub.Owner != "new-owner" {
+					t.Error("owner not reloaded")
+				}
+
+				if entries, _ := current.Cache.(CacheAdmin).Entries(); len(entries) != 0 {
+					t.Errorf("cache of the previous owner served: %v", entries)
+				}
+
+				if entries, _ := previous.Entries(); len(entries) != 0 {
+					t.Errorf("cache of the previous owner not purged: %v", entries)
+				}
+			},
+		},
+		{
+			name: "settings requiring a restart",
+			modify: func(config *Config) {
+				config.Listener.Addr = ":9999"
+				config.Cache.Backend = "bolt"
+			},
+			check: func(t *testing.T, current *AppContext, _ *cache.Namespace) {
+				if current.config.Listener.Addr != ":8493" || current.config.Cache.Backend != "memory" {
+					t.Error("settings requiring a restart changed")
+				}
+			},
+		},
+		{
+			name:    "invalid configuration",
+			loadErr: errors.New("invalid configuration"),
+			wantErr: true,
+		},
+		{
+			name: "webhook requires the github backend",
+			initial: func(config *Config) {
+				config.Backend.GitHub.WebhookSecretFile = secretFile
+			},
+			modify: func(config *Config) {
+				config.Backend.Type = "gitlab"
+				config.Backend.GitLab.Group = "the-group"
+			},
+			wantErr: true,
+		},
+		{
+			name: "proxy requires a supporting backend",
+			initial: func(config *Config) {
+				config.Listener.EnableProxy = true
+			},
+			modify: func(config *Config) {
+				config.Backend.Type = "gitlab"
+				config.Backend.GitLab.Group = "the-group"
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			initial := newReloadTestConfig()
+			if tt.initial != nil {
+				tt.initial(initial)
+			}
+
+			appContext, _ := newReloadTestAppContext(t, initial)
+			previous := appContext.Cache.(*cache.Namespace)
+			previous.Set("foo", "bar")
+
+			appContext.LoadConfig = func() (*Config, error) {
+				if tt.loadErr != nil {
+					return nil, tt.loadErr
+				}
+
+				config := newReloadTestConfig()
+				if tt.initial != nil {
+					tt.initial(config)
+				}
+				tt.modify(config)
+
+				return config, nil
+			}
+
+			err := appContext.Reload()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Reload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				if appContext.current() != appContext {
+					t.Error("settings swapped despite the error")
+				}
+				return
+			}
+
+			if appContext.current() == appContext {
+				t.Fatal("settings not swapped")
+			}
+
+			tt.check(t, appContext.current(), previous)
+		})
+	}
+}
+
+func Test_appContext_Reload_vcsHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(config *Config)
+		wantSame bool
+	}{
+		{
+			name:     "backend unchanged",
+			modify:   func(config *Config) { config.Cache.TTL = 2 * time.Hour },
+			wantSame: true,
+		},
+		{
+			name:   "backend changed",
+			modify: func(config *Config) { config.Backend.GitHub.RequestRate = 1 },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appContext, _ := newReloadTestAppContext(t, newReloadTestConfig())
+			previous := appContext.VCSHandler
+			appContext.LoadConfig = func() (*Config, error) {
+				config := newReloadTestConfig()
+				tt.modify(config)
+				return config, nil
+			}
+
+			if err := appContext.Reload(); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if same := appContext.current().VCSHandler == previous; same != tt.wantSame {
+				t.Errorf("VCS handler kept = %v, want %v", same, tt.wantSame)
+			}
+		})
+	}
+}
+
+func Test_appContext_Reload_inFlight(t *testing.T) {
+	appContext, _ := newReloadTestAppContext(t, newReloadTestConfig())
+	responseBuilder := &blockingResponseBuilder{started: make(chan struct{}), release: make(chan struct{})}
+	appContext.VCSHandler = &mockVCSHandler{fetchResult: &mockRepository{}}
+	appContext.ResponseBuilder = responseBuilder
+	appContext.LoadConfig = func() (*Config, error) {
+		config := newReloadTestConfig()
+		config.Cache.TTL = 2 * time.Hour
+		return config, nil
+	}
+
+	mux := appContext.getMux()
+	response := httptest.NewRecorder()
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		mux.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/foo", nil))
+	}()
+
+	<-responseBuilder.started
+
+	if err := appContext.Reload(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	close(responseBuilder.release)
+	<-done
+
+	if response.Code != http.StatusOK || response.Body.String() != "previous" {
+		t.Errorf("request in flight failed: %d %s", response.Code, response.Body)
+	}
+
+	if got := response.Header().Get("Cache-Control"); got != "public, max-age=3600" {
+		t.Errorf("request in flight uses the reloaded settings: %q", got)
+	}
+
+	if appContext.current().MaxAge != 2*time.Hour {
+		t.Error("settings not reloaded")
+	}
+}
+
+func Test_appContext_Reload_fetchInFlight(t *testing.T) {
+	appContext, _ := newReloadTestAppContext(t, newReloadTestConfig())
+	vcsHandler := &mockBlockingVCSHandler{mockVCSHandler: mockVCSHandler{fetchResult: &mockRepository{}}, started: make(chan struct{}), release: make(chan struct{})}
+	appContext.VCSHandler = vcsHandler
+	appContext.LoadConfig = func() (*Config, error) {
+		config := newReloadTestConfig()
+		config.Backend.GitHub.Owner = "new-owner"
+		return config, nil
+	}
+
+	mux := appContext.getMux()
+	response := httptest.NewRecorder()
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		mux.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/foo", nil))
+	}()
+
+	<-vcsHandler.started
+
+	if err := appContext.Reload(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	close(vcsHandler.release)
+	<-done
+
+	if response.Code != http.StatusOK {
+		t.Errorf("request in flight failed: %d %s", response.Code, response.Body)
+	}
+
+	current := appContext.current()
+
+	if entries, _ := current.Cache.(CacheAdmin).Entries(); len(entries) != 0 {
+		t.Errorf("repository of the previous owner cached for the new one: %v", entries)
+	}
+
+	if _, _, cached := current.Cache.Memoize("foo", func() (any, error) { return &mockRepository{}, nil }); cached {
+		t.Error("repository of the previous owner served for the new one")
+	}
+}
+
+func Test_appContext_Reload_withoutLoader(t *testing.T) {
+	if err := (&AppContext{}).Reload(); err == nil {
+		t.Error("no error")
+	}
+}
+
+type mockRateLimitReporterVCSHandler struct {
+	mockVCSHandler
+	mockRateLimitReporter
+}
+
+func Test_appContext_RateLimitRemaining(t *testing.T) {
+	appContext := &AppContext{VCSHandler: &mockVCSHandler{}}
+
+	if _, ok := appContext.RateLimitRemaining(); ok {
+		t.Error("rate limit reported without a reporting VCS backend")
+	}
+
+	appContext.reloaded.Store(&AppContext{VCSHandler: &mockRateLimitReporterVCSHandler{mockRateLimitReporter: mockRateLimitReporter{remaining: 42, ok: true}}})
+
+	if remaining, ok := appContext.RateLimitRemaining(); remaining != 42 || !ok {
+		t.Errorf("RateLimitRemaining() = %d, %v after reloading", remaining, ok)
+	}
+}
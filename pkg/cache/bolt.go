@@ -0,0 +1,181 @@
+package cache
+
+import (
+	"encoding/binary"
+	"errors"
+	bolt "go.etcd.io/bbolt"
+	"time"
+)
+
+var bucketName = []byte("cache")
+
+// deadlineSize is the size of the deadline, which precedes each encoded entry, so that expired entries can be swept
+// without decoding them.
+const deadlineSize = 8
+
+var errInvalidBoltEntry = errors.New("invalid bolt cache entry")
+
+type BoltStore struct {
+	db   *bolt.DB
+	now  func() time.Time
+	done chan struct{}
+}
+
+// NewBoltStore opens the database at path, deleting entries past their retention every sweepInterval (0 disables
+// sweeping).
+func NewBoltStore(path string, sweepInterval time.Duration) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketName)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	s := &BoltStore{
+		db:   db,
+		now:  time.Now,
+		done: make(chan struct{}),
+	}
+
+	if sweepInterval > 0 {
+		go s.sweep(sweepInterval)
+	}
+
+	return s, nil
+}
+
+func (s *BoltStore) sweep(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_ = s.deleteExpired()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *BoltStore) deleteExpired() error {
+	now := s.now()
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketName)
+		expired := [][]byte{}
+
+		err := bucket.ForEach(func(key, data []byte) error {
+			// Entries that can't be decoded are treated as a miss anyway.
+			if deadline, _, err := splitDeadline(data); err != nil || !now.Before(deadline) {
+				expired = append(expired, append([]byte{}, key...))
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, key := range expired {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func splitDeadline(data []byte) (time.Time, []byte, error) {
+	if len(data) < deadlineSize {
+		return time.Time{}, nil, errInvalidBoltEntry
+	}
+
+	return time.Unix(0, int64(binary.BigEndian.Uint64(data))), data[deadlineSize:], nil
+}
+
+func (s *BoltStore) Get(key string) (*Entry, bool, error) {
+	var entry *Entry
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketName).Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+
+		deadline, data, err := splitDeadline(data)
+		if err != nil || !s.now().Before(deadline) {
+			return err
+		}
+
+		entry, err = decodeEntry(data)
+
+		return err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return entry, entry != nil, nil
+}
+
+func (s *BoltStore) Set(key string, entry *Entry, ttl time.Duration) error {
+	data, err := encodeEntry(entry)
+	if err != nil {
+		return err
+	}
+
+	// bbolt doesn't expire keys, so the deadline is stored with the entry and expired entries are swept periodically.
+	data = append(binary.BigEndian.AppendUint64(make([]byte, 0, deadlineSize+len(data)), uint64(s.now().Add(ttl).UnixNano())), data...)
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).Put([]byte(key), data)
+	})
+}
+
+func (s *BoltStore) Delete(key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).Delete([]byte(key))
+	})
+}
+
+func (s *BoltStore) Range(fn func(key string, entry *Entry)) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).ForEach(func(key, data []byte) error {
+			// Entries that can't be decoded are treated as a miss anyway.
+			if _, data, err := splitDeadline(data); err == nil {
+				if entry, err := decodeEntry(data); err == nil {
+					fn(string(key), entry)
+				}
+			}
+
+			return nil
+		})
+	})
+}
+
+func (s *BoltStore) Clear() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(bucketName); err != nil {
+			return err
+		}
+
+		_, err := tx.CreateBucket(bucketName)
+
+		return err
+	})
+}
+
+func (s *BoltStore) Close() error {
+	close(s.done)
+
+	return s.db.Close()
+}
@@ -0,0 +1,184 @@
+package cache
+
+import (
+	bolt "go.etcd.io/bbolt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBoltStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+
+	store, err := NewBoltStore(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok, err := store.Get("key"); ok || err != nil {
+		t.Errorf("Get() = %v, %v for missing key", ok, err)
+	}
+	expiration := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := store.Set("key", &Entry{Expiration: expiration, Value: &mockValue{Name: "foo"}}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err = NewBoltStore(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+
+	want := &Entry{Expiration: expiration, Value: &mockValue{Name: "foo"}}
+	if got, ok, err := store.Get("key"); !ok || err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Get() = %+v, %v, %v after reopening", got, ok, err)
+	}
+
+	if err := store.Delete("key"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := store.Get("key"); ok {
+		t.Error("Get() found deleted key")
+	}
+}
+
+func TestBoltStore_memoizer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	fn := func() (any, error) { return &mockValue{Name: "foo"}, nil }
+
+	store, err := NewBoltStore(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, _ = NewMemoizer(store, time.Hour, 0, 0).Memoize("key", fn)
+	_ = store.Close()
+
+	store, err = NewBoltStore(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+
+	got, err, cached := NewMemoizer(store, time.Hour, 0, 0).Memoize("key", fn)
+	if err != nil || !cached || got.(*mockValue).Name != "foo" {
+		t.Errorf("Memoize() = %v, %v, %v after reopening", got, err, cached)
+	}
+}
+
+func TestNewBoltStore_invalidPath(t *testing.T) {
+	if _, err := NewBoltStore(filepath.Join(t.TempDir(), "missing", "cache.db"), 0); err == nil {
+		t.Error("NewBoltStore() no error")
+	}
+}
+
+func TestBoltStore_encodingErrors(t *testing.T) {
+	store, err := NewBoltStore(filepath.Join(t.TempDir(), "cache.db"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+
+	if err := store.Set("key", &Entry{Value: struct{ Name string }{Name: "foo"}}, time.Hour); err == nil {
+		t.Error("Set() no error for unregistered type")
+	}
+
+	err = store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).Put([]byte("key"), []byte("invalid"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := store.Get("key"); err == nil {
+		t.Error("Get() no error for invalid entry")
+	}
+}
+
+func TestBoltStore_RangeClear(t *testing.T) {
+	store, err := NewBoltStore(filepath.Join(t.TempDir(), "cache.db"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+
+	_ = store.Set("foo", &Entry{Value: "foo"}, time.Hour)
+	_ = store.Set("bar", &Entry{Value: "bar"}, time.Hour)
+	_ = store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).Put([]byte("invalid"), []byte("invalid"))
+	})
+
+	got := map[string]any{}
+	if err := store.Range(func(key string, entry *Entry) { got[key] = entry.Value }); err != nil {
+		t.Fatalf("Range() error = %v", err)
+	}
+	if want := map[string]any{"foo": "foo", "bar": "bar"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Range() got = %v, want %v", got, want)
+	}
+
+	if err := store.Clear(); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if _, ok, _ := store.Get("foo"); ok {
+		t.Error("Get() found cleared key")
+	}
+	if err := store.Set("foo", &Entry{Value: "foo"}, time.Hour); err != nil {
+		t.Errorf("Set() error = %v after clearing", err)
+	}
+}
+
+func TestBoltStore_expiration(t *testing.T) {
+	store, err := NewBoltStore(filepath.Join(t.TempDir(), "cache.db"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	_ = store.Set("expired", &Entry{Value: "expired"}, time.Hour)
+	_ = store.Set("fresh", &Entry{Value: "fresh"}, 3*time.Hour)
+	now = now.Add(2 * time.Hour)
+
+	if _, ok, err := store.Get("expired"); ok || err != nil {
+		t.Errorf("Get() = %v, %v for expired key", ok, err)
+	}
+
+	if err := store.deleteExpired(); err != nil {
+		t.Fatalf("deleteExpired() error = %v", err)
+	}
+
+	got := map[string]any{}
+	_ = store.Range(func(key string, entry *Entry) { got[key] = entry.Value })
+	if want := map[string]any{"fresh": "fresh"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Range() got = %v, want %v after sweeping", got, want)
+	}
+}
+
+func TestBoltStore_sweep(t *testing.T) {
+	store, err := NewBoltStore(filepath.Join(t.TempDir(), "cache.db"), 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+
+	_ = store.Set("key", &Entry{Value: "foo"}, time.Millisecond)
+
+	for range 100 {
+		count := 0
+		_ = store.db.View(func(tx *bolt.Tx) error {
+			count = tx.Bucket(bucketName).Stats().KeyN
+			return nil
+		})
+		if count == 0 {
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Error("expired key not swept")
+}
@@ -0,0 +1,386 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"go.eigsys.de/masquerade/pkg/repository"
+	"golang.org/x/sync/singleflight"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const keySeparator = "#"
+
+type Store interface {
+	Get(key string) (*Entry, bool, error)
+	Set(key string, entry *Entry, ttl time.Duration) error
+	Delete(key string) error
+	Range(fn func(key string, entry *Entry)) error
+	Clear() error
+	Close() error
+}
+
+type Status string
+
+const (
+	StatusMiss        Status = "Miss"
+	StatusHit         Status = "Hit"
+	StatusNegativeHit Status = "Negative-Hit"
+	StatusStale       Status = "Stale"
+)
+
+type Entry struct {
+	Expiration time.Time
+	MaxStale   time.Duration
+	Value      any
+	NotFound   bool
+}
+
+type EntryInfo struct {
+	Key        string    `json:"key"`
+	Expiration time.Time `json:"expiration"`
+	Stale      bool      `json:"stale"`
+	NotFound   bool      `json:"not_found"`
+}
+
+func Key(module string, parts ...string) string {
+	return strings.Join(append([]string{module}, parts...), keySeparator)
+}
+
+// isModuleKey reports whether key belongs to module. Repository names are case-insensitive, so keys of requests for
+// "Repo" belong to "repo" as well.
+func isModuleKey(key, module string) bool {
+	if len(key) < len(module) || !strings.EqualFold(key[:len(module)], module) {
+		return false
+	}
+
+	return len(key) == len(module) || strings.HasPrefix(key[len(module):], keySeparator)
+}
+
+func Register(values ...any) {
+	for _, value := range values {
+		gob.Register(value)
+	}
+}
+
+// Memoized functions are shared between requests and may run in the background, so they must not be canceled with a single request.
+func Detach(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) (any, error)) func() (any, error) {
+	return func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+		defer cancel()
+
+		return fn(ctx)
+	}
+}
+
+func encodeEntry(entry *Entry) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+
+	if err := gob.NewEncoder(buffer).Encode(entry); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func decodeEntry(data []byte) (*Entry, error) {
+	entry := &Entry{}
+
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(entry); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+type Memoizer struct {
+	store       Store
+	mu          sync.RWMutex
+	ttl         time.Duration
+	negativeTTL time.Duration
+	maxStale    time.Duration
+	revalidate  time.Duration
+	group       singleflight.Group
+	coalesced   atomic.Uint64
+	now         func() time.Time
+}
+
+type MemoizerOption func(m *Memoizer)
+
+// WithRevalidation keeps expired entries for the given duration, so that they can be passed to MemoizeRevalidate.
+func WithRevalidation(revalidate time.Duration) MemoizerOption {
+	return func(m *Memoizer) {
+		m.revalidate = revalidate
+	}
+}
+
+func NewMemoizer(store Store, ttl, negativeTTL, maxStale time.Duration, options ...MemoizerOption) *Memoizer {
+	m := &Memoizer{
+		store:       store,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		maxStale:    maxStale,
+		now:         time.Now,
+	}
+
+	for _, option := range options {
+		option(m)
+	}
+
+	return m
+}
+
+func (m *Memoizer) Memoize(key string, fn func() (any, error)) (any, error, bool) {
+	value, err, status := m.MemoizeStatus(key, fn)
+
+	return value, err, status != StatusMiss
+}
+
+func (m *Memoizer) MemoizeStatus(key string, fn func() (any, error)) (any, error, Status) {
+	return m.MemoizeRevalidate(key, func(_ any) (any, error) {
+		return fn()
+	})
+}
+
+// MemoizeRevalidate is like MemoizeStatus, but passes the expired value (or nil) to fn, so that it can be revalidated.
+func (m *Memoizer) MemoizeRevalidate(key string, fn func(previous any) (any, error)) (any, error, Status) {
+	var previous any
+
+	if entry, ok := m.get(key); ok {
+		now := m.now()
+
+		switch {
+		case now.Before(entry.Expiration) && entry.NotFound:
+			return nil, repository.ErrNotFound, StatusNegativeHit
+		case now.Before(entry.Expiration):
+			return entry.Value, nil, StatusHit
+		case entry.NotFound:
+		case now.Before(entry.Expiration.Add(entry.MaxStale)):
+			go m.refresh(key, entry.Value, fn)
+			return entry.Value, nil, StatusStale
+		default:
+			previous = entry.Value
+		}
+	}
+
+	leader := false
+	value, err, shared := m.group.Do(key, func() (any, error) {
+		leader = true
+		return m.load(key, previous, fn)
+	})
+	if shared && !leader {
+		m.coalesced.Add(1)
+	}
+
+	return value, err, StatusMiss
+}
+
+// SetTTL changes the TTLs of entries stored from now on, whereas existing entries keep their expiration.
+func (m *Memoizer) SetTTL(ttl, negativeTTL, maxStale time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ttl, m.negativeTTL, m.maxStale = ttl, negativeTTL, maxStale
+}
+
+func (m *Memoizer) ttls() (ttl, negativeTTL, maxStale time.Duration) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.ttl, m.negativeTTL, m.maxStale
+}
+
+// Coalesced returns the number of calls that waited for the result of a concurrent call instead of calling fn.
+func (m *Memoizer) Coalesced() uint64 {
+	return m.coalesced.Load()
+}
+
+func (m *Memoizer) Set(key string, value any) {
+	ttl, _, maxStale := m.ttls()
+	m.set(key, &Entry{Value: value}, ttl, maxStale)
+}
+
+func (m *Memoizer) refresh(key string, previous any, fn func(previous any) (any, error)) {
+	_, err, _ := m.group.Do(key, func() (any, error) {
+		return m.load(key, previous, fn)
+	})
+	if err != nil {
+		// The stale entry is kept, so it's served until it's refreshed successfully or exceeds the max. staleness.
+		log.Printf("cache: refreshing %q: %s", key, err)
+	}
+}
+
+func (m *Memoizer) load(key string, previous any, fn func(previous any) (any, error)) (any, error) {
+	value, err := fn(previous)
+	ttl, negativeTTL, maxStale := m.ttls()
+
+	if err != nil {
+		if negativeTTL > 0 && errors.Is(err, repository.ErrNotFound) {
+			m.set(key, &Entry{NotFound: true}, negativeTTL, 0)
+		}
+
+		return nil, err
+	}
+
+	m.set(key, &Entry{Value: value}, ttl, maxStale)
+
+	return value, nil
+}
+
+func (m *Memoizer) get(key string) (*Entry, bool) {
+	entry, ok, err := m.store.Get(key)
+	if err != nil {
+		// Entries written by another version may no longer decode, so they are treated as a miss.
+		log.Printf("cache: reading %q: %s", key, err)
+		return nil, false
+	}
+
+	if !ok {
+		return nil, false
+	}
+
+	if !m.now().Before(entry.Expiration.Add(entry.MaxStale + m.revalidate)) {
+		if err := m.store.Delete(key); err != nil {
+			log.Printf("cache: deleting %q: %s", key, err)
+		}
+
+		return nil, false
+	}
+
+	return entry, true
+}
+
+func (m *Memoizer) set(key string, entry *Entry, ttl, maxStale time.Duration) {
+	entry.Expiration = m.now().Add(ttl)
+	entry.MaxStale = maxStale
+
+	retention := ttl + maxStale
+	if !entry.NotFound {
+		retention += m.revalidate
+	}
+
+	if err := m.store.Set(key, entry, retention); err != nil {
+		log.Printf("cache: storing %q: %s", key, err)
+	}
+}
+
+func (m *Memoizer) Entries() ([]EntryInfo, error) {
+	entries := []EntryInfo{}
+	now := m.now()
+
+	err := m.store.Range(func(key string, entry *Entry) {
+		if !now.Before(entry.Expiration.Add(entry.MaxStale)) {
+			return
+		}
+
+		entries = append(entries, EntryInfo{
+			Key:        key,
+			Expiration: entry.Expiration,
+			Stale:      !now.Before(entry.Expiration),
+			NotFound:   entry.NotFound,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+
+	return entries, nil
+}
+
+func (m *Memoizer) Invalidate(module string) (int, error) {
+	keys := []string{}
+
+	err := m.store.Range(func(key string, _ *Entry) {
+		if isModuleKey(key, module) {
+			keys = append(keys, key)
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, key := range keys {
+		if err := m.store.Delete(key); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(keys), nil
+}
+
+func (m *Memoizer) Clear() error {
+	return m.store.Clear()
+}
+
+// Namespace is a view of a Memoizer whose keys are prefixed with a name, so that sources sharing a store never share
+// entries. The TTLs and the coalesced calls are those of the Memoizer.
+type Namespace struct {
+	*Memoizer
+	name string
+}
+
+// Namespace returns a view of m with the keys in the given namespace. It's also promoted to Namespace, where it
+// returns a sibling namespace instead of a nested one.
+func (m *Memoizer) Namespace(name string) *Namespace {
+	return &Namespace{Memoizer: m, name: name}
+}
+
+func (n *Namespace) key(key string) string {
+	return Key(n.name, key)
+}
+
+func (n *Namespace) Memoize(key string, fn func() (any, error)) (any, error, bool) {
+	return n.Memoizer.Memoize(n.key(key), fn)
+}
+
+func (n *Namespace) MemoizeStatus(key string, fn func() (any, error)) (any, error, Status) {
+	return n.Memoizer.MemoizeStatus(n.key(key), fn)
+}
+
+func (n *Namespace) MemoizeRevalidate(key string, fn func(previous any) (any, error)) (any, error, Status) {
+	return n.Memoizer.MemoizeRevalidate(n.key(key), fn)
+}
+
+func (n *Namespace) Set(key string, value any) {
+	n.Memoizer.Set(n.key(key), value)
+}
+
+// Entries returns the entries of the namespace, with the keys relative to it.
+func (n *Namespace) Entries() ([]EntryInfo, error) {
+	all, err := n.Memoizer.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := n.key("")
+	entries := []EntryInfo{}
+
+	for _, entry := range all {
+		if key, ok := strings.CutPrefix(entry.Key, prefix); ok {
+			entry.Key = key
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+func (n *Namespace) Invalidate(module string) (int, error) {
+	return n.Memoizer.Invalidate(n.key(module))
+}
+
+// Clear removes the entries of the namespace, and keeps those of other namespaces.
+func (n *Namespace) Clear() error {
+	_, err := n.Memoizer.Invalidate(n.name)
+	return err
+}
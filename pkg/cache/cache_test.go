@@ -0,0 +1,705 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"go.eigsys.de/masquerade/pkg/repository"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type mockValue struct {
+	Name string
+}
+
+func init() {
+	Register(&mockValue{})
+}
+
+type mockStore struct {
+	mutex     sync.Mutex
+	values    map[string]*Entry
+	getErr    error
+	setErr    error
+	deleteErr error
+}
+
+func newMockStore() *mockStore {
+	return &mockStore{values: map[string]*Entry{}}
+}
+
+func (m *mockStore) Get(key string) (*Entry, bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	value, ok := m.values[key]
+
+	return value, ok, m.getErr
+}
+
+func (m *mockStore) Set(key string, entry *Entry, _ time.Duration) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.setErr != nil {
+		return m.setErr
+	}
+
+	m.values[key] = entry
+
+	return nil
+}
+
+func (m *mockStore) Delete(key string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.deleteErr != nil {
+		return m.deleteErr
+	}
+
+	delete(m.values, key)
+
+	return nil
+}
+
+func (m *mockStore) Range(fn func(key string, entry *Entry)) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.getErr != nil {
+		return m.getErr
+	}
+
+	for key, entry := range m.values {
+		fn(key, entry)
+	}
+
+	return nil
+}
+
+func (m *mockStore) Clear() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.values = map[string]*Entry{}
+
+	return nil
+}
+
+func (m *mockStore) Close() error {
+	return nil
+}
+
+func TestMemoizer_Memoize(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+	}{
+		{name: "registered-type", value: &mockValue{Name: "foo"}},
+		{name: "string-slice", value: []string{"a", "b"}},
+		{name: "bool", value: true},
+		{name: "time", value: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+	}
+	Register(time.Time{})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMemoizer(newMockStore(), time.Hour, 0, 0)
+			calls := 0
+			fn := func() (any, error) {
+				calls++
+				return tt.value, nil
+			}
+
+			got, err, cached := m.Memoize("key", fn)
+			if err != nil || cached || !reflect.DeepEqual(got, tt.value) {
+				t.Fatalf("Memoize() = %v, %v, %v", got, err, cached)
+			}
+
+			got, err, cached = m.Memoize("key", fn)
+			if err != nil || !cached || !reflect.DeepEqual(got, tt.value) {
+				t.Errorf("Memoize() = %v, %v, %v", got, err, cached)
+			}
+			if calls != 1 {
+				t.Errorf("Memoize() called fn %d times", calls)
+			}
+		})
+	}
+}
+
+func TestMemoizer_Memoize_expiration(t *testing.T) {
+	store := newMockStore()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	m := NewMemoizer(store, time.Hour, 0, 0)
+	m.now = func() time.Time { return now }
+	calls := 0
+	fn := func() (any, error) {
+		calls++
+		return calls, nil
+	}
+
+	_, _, _ = m.Memoize("key", fn)
+	now = now.Add(59 * time.Minute)
+	if got, _, cached := m.Memoize("key", fn); got != 1 || !cached {
+		t.Errorf("Memoize() = %v, %v before expiration", got, cached)
+	}
+
+	now = now.Add(time.Minute)
+	if got, _, cached := m.Memoize("key", fn); got != 2 || cached {
+		t.Errorf("Memoize() = %v, %v after expiration", got, cached)
+	}
+}
+
+func TestMemoizer_Memoize_error(t *testing.T) {
+	store := newMockStore()
+	m := NewMemoizer(store, time.Hour, 0, 0)
+	wantErr := errors.New("error")
+
+	if _, err, _ := m.Memoize("key", func() (any, error) { return nil, wantErr }); !errors.Is(err, wantErr) {
+		t.Errorf("Memoize() error = %v, wantErr %v", err, wantErr)
+	}
+	if len(store.values) != 0 {
+		t.Error("error has been cached")
+	}
+}
+
+func TestMemoizer_Memoize_storeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		store *mockStore
+		value any
+	}{
+		{name: "get-error", store: &mockStore{values: map[string]*Entry{}, getErr: errors.New("error")}, value: "foo"},
+		{name: "set-error", store: &mockStore{values: map[string]*Entry{}, setErr: errors.New("error")}, value: "foo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMemoizer(tt.store, time.Hour, 0, 0)
+
+			got, err, cached := m.Memoize("key", func() (any, error) { return tt.value, nil })
+			if err != nil || cached || !reflect.DeepEqual(got, tt.value) {
+				t.Errorf("Memoize() = %v, %v, %v", got, err, cached)
+			}
+		})
+	}
+}
+
+func TestMemoizer_Memoize_deleteError(t *testing.T) {
+	store := newMockStore()
+	now := time.Now()
+	m := NewMemoizer(store, time.Hour, 0, 0)
+	m.now = func() time.Time { return now }
+
+	_, _, _ = m.Memoize("key", func() (any, error) { return "foo", nil })
+	store.deleteErr = errors.New("error")
+	now = now.Add(time.Hour)
+
+	if got, err, cached := m.Memoize("key", func() (any, error) { return "bar", nil }); got != "bar" || err != nil || cached {
+		t.Errorf("Memoize() = %v, %v, %v", got, err, cached)
+	}
+}
+
+func TestMemoizer_Memoize_concurrent(t *testing.T) {
+	m := NewMemoizer(newMockStore(), time.Hour, 0, 0)
+	release := make(chan struct{})
+	var calls atomic.Int32
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, _ = m.Memoize("key", func() (any, error) {
+				calls.Add(1)
+				<-release
+				return "foo", nil
+			})
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("Memoize() called fn %d times", calls.Load())
+	}
+	if m.Coalesced() != 9 {
+		t.Errorf("Coalesced() = %d", m.Coalesced())
+	}
+}
+
+func TestMemoizer_Memoize_canceledCaller(t *testing.T) {
+	m := NewMemoizer(newMockStore(), time.Hour, 0, 0)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	fn := func(ctx context.Context) (any, error) {
+		close(started)
+		<-release
+
+		return "foo", ctx.Err()
+	}
+	leaderDone := make(chan error)
+
+	go func() {
+		_, err, _ := m.Memoize("key", Detach(ctx, time.Minute, fn))
+		leaderDone <- err
+	}()
+
+	<-started
+	followerDone := make(chan any)
+
+	go func() {
+		got, _, _ := m.Memoize("key", func() (any, error) { return nil, errors.New("not coalesced") })
+		followerDone <- got
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	close(release)
+
+	if err := <-leaderDone; err != nil {
+		t.Errorf("Memoize() error = %v for the canceled caller", err)
+	}
+	if got := <-followerDone; got != "foo" {
+		t.Errorf("Memoize() = %v for the waiting caller", got)
+	}
+}
+
+func TestMemoizer_Memoize_negative(t *testing.T) {
+	store := newMockStore()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	m := NewMemoizer(store, time.Hour, time.Minute, 0)
+	m.now = func() time.Time { return now }
+	calls := 0
+	fn := func() (any, error) {
+		calls++
+		if calls == 1 {
+			return nil, repository.ErrNotFound
+		}
+
+		return "foo", nil
+	}
+
+	if _, err, cached := m.Memoize("key", fn); !errors.Is(err, repository.ErrNotFound) || cached {
+		t.Errorf("Memoize() error = %v, cached = %v on miss", err, cached)
+	}
+	if _, err, cached := m.Memoize("key", fn); !errors.Is(err, repository.ErrNotFound) || !cached {
+		t.Errorf("Memoize() error = %v, cached = %v on negative hit", err, cached)
+	}
+
+	now = now.Add(time.Minute)
+	if got, err, cached := m.Memoize("key", fn); got != "foo" || err != nil || cached {
+		t.Errorf("Memoize() = %v, %v, %v after negative expiration", got, err, cached)
+	}
+	if calls != 2 {
+		t.Errorf("Memoize() called fn %d times", calls)
+	}
+}
+
+func TestMemoizer_Memoize_negativeDisabled(t *testing.T) {
+	store := newMockStore()
+	m := NewMemoizer(store, time.Hour, 0, 0)
+
+	_, _, _ = m.Memoize("key", func() (any, error) { return nil, repository.ErrNotFound })
+	if len(store.values) != 0 {
+		t.Error("not found error has been cached")
+	}
+}
+
+func waitForStatus(t *testing.T, m *Memoizer, key string, want Status) any {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		value, _, status := m.MemoizeStatus(key, func() (any, error) { return nil, errors.New("unexpected call") })
+		if status == want {
+			return value
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("MemoizeStatus() never returned %v", want)
+
+	return nil
+}
+
+func TestMemoizer_MemoizeStatus_stale(t *testing.T) {
+	store := newMockStore()
+	var mutex sync.Mutex
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	m := NewMemoizer(store, time.Hour, 0, 24*time.Hour)
+	m.now = func() time.Time {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		return now
+	}
+	advance := func(d time.Duration) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		now = now.Add(d)
+	}
+
+	if _, _, status := m.MemoizeStatus("key", func() (any, error) { return "old", nil }); status != StatusMiss {
+		t.Fatalf("MemoizeStatus() status = %v", status)
+	}
+
+	advance(time.Hour)
+	refreshed := make(chan struct{})
+	got, err, status := m.MemoizeStatus("key", func() (any, error) {
+		defer close(refreshed)
+		return "new", nil
+	})
+	if got != "old" || err != nil || status != StatusStale {
+		t.Errorf("MemoizeStatus() = %v, %v, %v for stale entry", got, err, status)
+	}
+
+	<-refreshed
+	if got := waitForStatus(t, m, "key", StatusHit); got != "new" {
+		t.Errorf("MemoizeStatus() = %v after refresh", got)
+	}
+}
+
+func TestMemoizer_MemoizeStatus_staleOnError(t *testing.T) {
+	store := newMockStore()
+	var mutex sync.Mutex
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	m := NewMemoizer(store, time.Hour, time.Minute, 24*time.Hour)
+	m.now = func() time.Time {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		return now
+	}
+	advance := func(d time.Duration) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		now = now.Add(d)
+	}
+	calls := make(chan struct{}, 10)
+	fail := func() (any, error) {
+		calls <- struct{}{}
+		return nil, errors.New("error")
+	}
+
+	_, _, _ = m.MemoizeStatus("key", func() (any, error) { return "old", nil })
+	advance(2 * time.Hour)
+
+	for i := 0; i < 2; i++ {
+		if got, err, status := m.MemoizeStatus("key", fail); got != "old" || err != nil || status != StatusStale {
+			t.Errorf("MemoizeStatus() = %v, %v, %v while the backend fails", got, err, status)
+		}
+		<-calls
+	}
+
+	advance(23 * time.Hour)
+	if _, err, status := m.MemoizeStatus("key", fail); err == nil || status != StatusMiss {
+		t.Errorf("MemoizeStatus() = %v, %v after max. staleness", err, status)
+	}
+}
+
+func TestMemoizer_MemoizeStatus_staleNotFound(t *testing.T) {
+	store := newMockStore()
+	var mutex sync.Mutex
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	m := NewMemoizer(store, time.Hour, time.Minute, 24*time.Hour)
+	m.now = func() time.Time {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		return now
+	}
+
+	_, _, _ = m.MemoizeStatus("key", func() (any, error) { return "old", nil })
+	mutex.Lock()
+	now = now.Add(time.Hour)
+	mutex.Unlock()
+
+	_, _, _ = m.MemoizeStatus("key", func() (any, error) { return nil, repository.ErrNotFound })
+	waitForStatus(t, m, "key", StatusNegativeHit)
+
+	mutex.Lock()
+	now = now.Add(time.Minute)
+	mutex.Unlock()
+
+	if _, err, status := m.MemoizeStatus("key", func() (any, error) { return nil, repository.ErrNotFound }); !errors.Is(err, repository.ErrNotFound) || status != StatusMiss {
+		t.Errorf("MemoizeStatus() = %v, %v for expired negative entry", err, status)
+	}
+}
+
+func TestMemoizer_MemoizeRevalidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		options      []MemoizerOption
+		advance      time.Duration
+		wantPrevious any
+	}{
+		{
+			name:         "within-window",
+			options:      []MemoizerOption{WithRevalidation(time.Hour)},
+			advance:      90 * time.Minute,
+			wantPrevious: "old",
+		},
+		{
+			name:    "beyond-window",
+			options: []MemoizerOption{WithRevalidation(time.Hour)},
+			advance: 3 * time.Hour,
+		},
+		{
+			name:    "disabled",
+			advance: 90 * time.Minute,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+			m := NewMemoizer(newMockStore(), time.Hour, 0, 0, tt.options...)
+			m.now = func() time.Time { return now }
+
+			_, _, _ = m.MemoizeRevalidate("key", func(_ any) (any, error) { return "old", nil })
+			now = now.Add(tt.advance)
+
+			var gotPrevious any
+			got, err, status := m.MemoizeRevalidate("key", func(previous any) (any, error) {
+				gotPrevious = previous
+				return previous, nil
+			})
+			if gotPrevious != tt.wantPrevious {
+				t.Errorf("MemoizeRevalidate() previous = %v, want %v", gotPrevious, tt.wantPrevious)
+			}
+			if got != tt.wantPrevious || err != nil || status != StatusMiss {
+				t.Errorf("MemoizeRevalidate() = %v, %v, %v", got, err, status)
+			}
+
+			if _, _, status := m.MemoizeRevalidate("key", func(_ any) (any, error) { return "new", nil }); status != StatusHit {
+				t.Errorf("MemoizeRevalidate() status = %v after revalidation", status)
+			}
+		})
+	}
+}
+
+func TestDetach(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	got, err := Detach(ctx, time.Minute, func(ctx context.Context) (any, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			return nil, errors.New("no deadline")
+		}
+
+		return nil, ctx.Err()
+	})()
+	if got != nil || err != nil {
+		t.Errorf("Detach() = %v, %v", got, err)
+	}
+}
+
+func TestKey(t *testing.T) {
+	if got := Key("foo"); got != "foo" {
+		t.Errorf("Key() = %v", got)
+	}
+	if got := Key("foo", "dir", "sub/pkg"); got != "foo#dir#sub/pkg" {
+		t.Errorf("Key() = %v", got)
+	}
+}
+
+func newAdminTestMemoizer(t *testing.T) (*Memoizer, *mockStore) {
+	t.Helper()
+
+	store := newMockStore()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	m := NewMemoizer(store, time.Hour, time.Minute, time.Hour)
+	m.now = func() time.Time { return now }
+
+	_, _, _ = m.Memoize("foo", func() (any, error) { return "foo", nil })
+	_, _, _ = m.Memoize(Key("foo", "modules"), func() (any, error) { return []string{}, nil })
+	_, _, _ = m.Memoize("foobar", func() (any, error) { return "foobar", nil })
+	_, _, _ = m.Memoize("missing", func() (any, error) { return nil, repository.ErrNotFound })
+
+	now = now.Add(90 * time.Minute)
+	_, _, _ = m.Memoize("bar", func() (any, error) { return "bar", nil })
+	now = now.Add(-30 * time.Minute)
+
+	return m, store
+}
+
+func TestMemoizer_Entries(t *testing.T) {
+	m, _ := newAdminTestMemoizer(t)
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	want := []EntryInfo{
+		{Key: "bar", Expiration: start.Add(150 * time.Minute)},
+		{Key: "foo", Expiration: start.Add(time.Hour), Stale: true},
+		{Key: "foo#modules", Expiration: start.Add(time.Hour), Stale: true},
+		{Key: "foobar", Expiration: start.Add(time.Hour), Stale: true},
+	}
+
+	got, err := m.Entries()
+	if err != nil {
+		t.Fatalf("Entries() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Entries() got = %+v, want %+v", got, want)
+	}
+}
+
+func TestMemoizer_Entries_notFound(t *testing.T) {
+	m := NewMemoizer(newMockStore(), time.Hour, time.Minute, 0)
+	_, _, _ = m.Memoize("missing", func() (any, error) { return nil, repository.ErrNotFound })
+
+	got, err := m.Entries()
+	if err != nil || len(got) != 1 || !got[0].NotFound {
+		t.Errorf("Entries() = %+v, %v", got, err)
+	}
+}
+
+func TestMemoizer_Invalidate(t *testing.T) {
+	m, store := newAdminTestMemoizer(t)
+
+	got, err := m.Invalidate("foo")
+	if err != nil || got != 2 {
+		t.Errorf("Invalidate() = %v, %v", got, err)
+	}
+
+	keys := []string{}
+	for key := range store.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if want := []string{"bar", "foobar", "missing"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("Invalidate() kept %v, want %v", keys, want)
+	}
+}
+
+func Test_isModuleKey(t *testing.T) {
+	tests := []struct {
+		key    string
+		module string
+		want   bool
+	}{
+		{key: "foo", module: "foo", want: true},
+		{key: "foo#modules", module: "foo", want: true},
+		{key: "Foo#dir#sub", module: "foo", want: true},
+		{key: "foo", module: "FOO", want: true},
+		{key: "foobar", module: "foo", want: false},
+		{key: "fo", module: "foo", want: false},
+		{key: "bar#foo", module: "foo", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key+"/"+tt.module, func(t *testing.T) {
+			if got := isModuleKey(tt.key, tt.module); got != tt.want {
+				t.Errorf("isModuleKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoizer_Clear(t *testing.T) {
+	m, store := newAdminTestMemoizer(t)
+
+	if err := m.Clear(); err != nil || len(store.values) != 0 {
+		t.Errorf("Clear() = %v, %d entries left", err, len(store.values))
+	}
+}
+
+func TestMemoizer_storeErrors(t *testing.T) {
+	store := &mockStore{values: map[string]*Entry{}, getErr: errors.New("error")}
+	m := NewMemoizer(store, time.Hour, 0, 0)
+
+	if _, err := m.Entries(); err == nil {
+		t.Error("Entries() no error")
+	}
+	if _, err := m.Invalidate("foo"); err == nil {
+		t.Error("Invalidate() no error")
+	}
+
+	store = &mockStore{values: map[string]*Entry{"foo": {}}, deleteErr: errors.New("error")}
+	if _, err := NewMemoizer(store, time.Hour, 0, 0).Invalidate("foo"); err == nil {
+		t.Error("Invalidate() no error")
+	}
+}
+
+func TestMemoizer_Set(t *testing.T) {
+	m := NewMemoizer(newMockStore(), time.Hour, 0, 0)
+	_, _, _ = m.Memoize("key", func() (any, error) { return "old", nil })
+
+	m.Set("key", "new")
+
+	if got, err, cached := m.Memoize("key", func() (any, error) { return nil, errors.New("unexpected call") }); got != "new" || err != nil || !cached {
+		t.Errorf("Memoize() = %v, %v, %v after Set()", got, err, cached)
+	}
+}
+
+func TestMemoizer_SetTTL(t *testing.T) {
+	store := newMockStore()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	m := NewMemoizer(store, time.Hour, time.Minute, 0)
+	m.now = func() time.Time { return now }
+
+	_, _, _ = m.Memoize("old", func() (any, error) { return "old", nil })
+	m.SetTTL(2*time.Hour, 2*time.Minute, time.Hour)
+	_, _, _ = m.Memoize("new", func() (any, error) { return "new", nil })
+	_, _, _ = m.Memoize("missing", func() (any, error) { return nil, repository.ErrNotFound })
+
+	tests := []struct {
+		key            string
+		wantExpiration time.Time
+		wantMaxStale   time.Duration
+	}{
+		{key: "old", wantExpiration: now.Add(time.Hour)},
+		{key: "new", wantExpiration: now.Add(2 * time.Hour), wantMaxStale: time.Hour},
+		{key: "missing", wantExpiration: now.Add(2 * time.Minute)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			entry := store.values[tt.key]
+			if !entry.Expiration.Equal(tt.wantExpiration) || entry.MaxStale != tt.wantMaxStale {
+				t.Errorf("entry = %v, %v, want %v, %v", entry.Expiration, entry.MaxStale, tt.wantExpiration, tt.wantMaxStale)
+			}
+		})
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	store := newMockStore()
+	m := NewMemoizer(store, time.Hour, 0, 0)
+	n := m.Namespace("ns")
+	other := n.Namespace("other")
+
+	_, _, _ = n.Memoize("foo", func() (any, error) { return "ns", nil })
+	n.Set("foo#modules", "modules")
+	other.Set("foo", "other")
+	m.Set("foo", "plain")
+
+	if got, err, cached := n.Memoize("foo", func() (any, error) { return nil, errors.New("unexpected call") }); got != "ns" || err != nil || !cached {
+		t.Errorf("Memoize() = %v, %v, %v", got, err, cached)
+	}
+
+	entries, err := n.Entries()
+	if err != nil || len(entries) != 2 || entries[0].Key != "foo" || entries[1].Key != "foo#modules" {
+		t.Errorf("Entries() = %+v, %v", entries, err)
+	}
+
+	if _, ok := store.values["other#foo"]; !ok {
+		t.Error("sibling namespace nested")
+	}
+
+	if got, err := n.Invalidate("foo"); got != 2 || err != nil {
+		t.Errorf("Invalidate() = %v, %v", got, err)
+	}
+
+	n.Set("bar", "bar")
+	if err := n.Clear(); err != nil || len(store.values) != 2 {
+		t.Errorf("Clear() = %v, %d entries left", err, len(store.values))
+	}
+}
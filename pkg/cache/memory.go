@@ -0,0 +1,227 @@
+package cache
+
+import (
+	"container/list"
+	"encoding"
+	"sync"
+	"time"
+)
+
+// entryOverhead approximates the memory used by an entry besides its key and value.
+const entryOverhead = 128
+
+type memoryEntry struct {
+	key      string
+	entry    *Entry
+	deadline time.Time
+	size     int64
+}
+
+type MemoryStats struct {
+	Entries int
+	// NegativeEntries is the number of entries among Entries that cache a missing repository.
+	NegativeEntries int
+	Bytes           int64
+	Evictions       uint64
+}
+
+type MemoryStoreOption func(s *MemoryStore)
+
+// WithMaxEntries limits the number of entries, evicting the least recently used ones (0 means unlimited).
+func WithMaxEntries(maxEntries int) MemoryStoreOption {
+	return func(s *MemoryStore) {
+		s.maxEntries = maxEntries
+	}
+}
+
+// WithMaxBytes limits the approximate size of all entries, evicting the least recently used ones (0 means unlimited).
+func WithMaxBytes(maxBytes int64) MemoryStoreOption {
+	return func(s *MemoryStore) {
+		s.maxBytes = maxBytes
+	}
+}
+
+type MemoryStore struct {
+	mutex      sync.Mutex
+	entries    map[string]*list.Element
+	lru        *list.List
+	negative   int
+	bytes      int64
+	evictions  uint64
+	maxEntries int
+	maxBytes   int64
+	now        func() time.Time
+	done       chan struct{}
+}
+
+func NewMemoryStore(cleanupInterval time.Duration, options ...MemoryStoreOption) *MemoryStore {
+	s := &MemoryStore{
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+		now:     time.Now,
+		done:    make(chan struct{}),
+	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	if cleanupInterval > 0 {
+		go s.cleanup(cleanupInterval)
+	}
+
+	return s
+}
+
+func (s *MemoryStore) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.deleteExpired()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *MemoryStore) deleteExpired() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	for _, element := range s.entries {
+		if !now.Before(element.Value.(*memoryEntry).deadline) {
+			s.remove(element)
+		}
+	}
+}
+
+func (s *MemoryStore) Get(key string) (*Entry, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	element, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	e := element.Value.(*memoryEntry)
+	if !s.now().Before(e.deadline) {
+		return nil, false, nil
+	}
+
+	s.lru.MoveToFront(element)
+
+	return e.entry, true, nil
+}
+
+func (s *MemoryStore) Set(key string, entry *Entry, ttl time.Duration) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if element, ok := s.entries[key]; ok {
+		s.remove(element)
+	}
+
+	e := &memoryEntry{key: key, entry: entry, deadline: s.now().Add(ttl), size: entrySize(key, entry)}
+	s.entries[key] = s.lru.PushFront(e)
+	s.bytes += e.size
+	if entry.NotFound {
+		s.negative++
+	}
+
+	s.evict()
+
+	return nil
+}
+
+func (s *MemoryStore) evict() {
+	for s.lru.Len() > 0 && ((s.maxEntries > 0 && s.lru.Len() > s.maxEntries) || (s.maxBytes > 0 && s.bytes > s.maxBytes)) {
+		s.remove(s.lru.Back())
+		s.evictions++
+	}
+}
+
+func (s *MemoryStore) remove(element *list.Element) {
+	e := s.lru.Remove(element).(*memoryEntry)
+	delete(s.entries, e.key)
+	s.bytes -= e.size
+	if e.entry.NotFound {
+		s.negative--
+	}
+}
+
+func (s *MemoryStore) Delete(key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if element, ok := s.entries[key]; ok {
+		s.remove(element)
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) Range(fn func(key string, entry *Entry)) error {
+	s.mutex.Lock()
+	entries := make(map[string]*Entry, len(s.entries))
+	for key, element := range s.entries {
+		entries[key] = element.Value.(*memoryEntry).entry
+	}
+	s.mutex.Unlock()
+
+	for key, entry := range entries {
+		fn(key, entry)
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) Clear() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.entries = map[string]*list.Element{}
+	s.lru.Init()
+	s.negative = 0
+	s.bytes = 0
+
+	return nil
+}
+
+func (s *MemoryStore) Stats() MemoryStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return MemoryStats{Entries: s.lru.Len(), NegativeEntries: s.negative, Bytes: s.bytes, Evictions: s.evictions}
+}
+
+func (s *MemoryStore) Close() error {
+	close(s.done)
+
+	return nil
+}
+
+func entrySize(key string, entry *Entry) int64 {
+	size := int64(len(key) + entryOverhead)
+
+	switch value := entry.Value.(type) {
+	case encoding.BinaryMarshaler:
+		if data, err := value.MarshalBinary(); err == nil {
+			size += int64(len(data))
+		}
+	case string:
+		size += int64(len(value))
+	case []byte:
+		size += int64(len(value))
+	case []string:
+		for _, s := range value {
+			size += int64(len(s))
+		}
+	}
+
+	return size
+}
@@ -0,0 +1,208 @@
+package cache
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	store := NewMemoryStore(0)
+	store.now = func() time.Time { return now }
+	defer func() { _ = store.Close() }()
+
+	if _, ok, err := store.Get("key"); ok || err != nil {
+		t.Errorf("Get() = %v, %v for missing key", ok, err)
+	}
+
+	entry := &Entry{Value: "value"}
+	_ = store.Set("key", entry, time.Minute)
+	if got, ok, err := store.Get("key"); !ok || err != nil || got != entry {
+		t.Errorf("Get() = %v, %v, %v", got, ok, err)
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok, _ := store.Get("key"); ok {
+		t.Error("Get() found expired key")
+	}
+
+	_ = store.Set("key", entry, time.Minute)
+	_ = store.Delete("key")
+	if _, ok, _ := store.Get("key"); ok {
+		t.Error("Get() found deleted key")
+	}
+}
+
+func TestMemoryStore_deleteExpired(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	store := NewMemoryStore(0)
+	store.now = func() time.Time { return now }
+	defer func() { _ = store.Close() }()
+
+	_ = store.Set("expired", &Entry{}, time.Minute)
+	_ = store.Set("valid", &Entry{}, time.Hour)
+	now = now.Add(time.Minute)
+	store.deleteExpired()
+
+	if _, ok := store.entries["expired"]; ok {
+		t.Error("expired entry has not been deleted")
+	}
+	if _, ok := store.entries["valid"]; !ok {
+		t.Error("valid entry has been deleted")
+	}
+}
+
+func TestMemoryStore_cleanup(t *testing.T) {
+	store := NewMemoryStore(time.Millisecond)
+	defer func() { _ = store.Close() }()
+
+	_ = store.Set("key", &Entry{}, time.Nanosecond)
+
+	for i := 0; i < 100; i++ {
+		store.mutex.Lock()
+		n := len(store.entries)
+		store.mutex.Unlock()
+
+		if n == 0 {
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Error("expired entry has not been cleaned up")
+}
+
+func TestMemoryStore_RangeClear(t *testing.T) {
+	store := NewMemoryStore(0)
+	defer func() { _ = store.Close() }()
+
+	_ = store.Set("foo", &Entry{Value: "foo"}, time.Hour)
+	_ = store.Set("bar", &Entry{Value: "bar"}, time.Hour)
+
+	got := map[string]any{}
+	_ = store.Range(func(key string, entry *Entry) {
+		got[key] = entry.Value
+	})
+	if want := map[string]any{"foo": "foo", "bar": "bar"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Range() got = %v, want %v", got, want)
+	}
+
+	_ = store.Clear()
+	if _, ok, _ := store.Get("foo"); ok {
+		t.Error("Get() found cleared key")
+	}
+}
+
+func TestMemoryStore_evict(t *testing.T) {
+	tests := []struct {
+		name          string
+		options       []MemoryStoreOption
+		wantKeys      []string
+		wantEvictions uint64
+	}{
+		{
+			name:     "unbounded",
+			wantKeys: []string{"a", "b", "c"},
+		},
+		{
+			name:          "max-entries",
+			options:       []MemoryStoreOption{WithMaxEntries(2)},
+			wantKeys:      []string{"a", "c"},
+			wantEvictions: 1,
+		},
+		{
+			name:          "max-bytes",
+			options:       []MemoryStoreOption{WithMaxBytes(2 * (entryOverhead + 1 + 5))},
+			wantKeys:      []string{"a", "c"},
+			wantEvictions: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore(0, tt.options...)
+			defer func() { _ = store.Close() }()
+
+			_ = store.Set("a", &Entry{Value: "value"}, time.Hour)
+			_ = store.Set("b", &Entry{Value: "value"}, time.Hour)
+			_, _, _ = store.Get("a")
+			_ = store.Set("c", &Entry{Value: "value"}, time.Hour)
+
+			keys := []string{}
+			for _, key := range []string{"a", "b", "c"} {
+				if _, ok, _ := store.Get(key); ok {
+					keys = append(keys, key)
+				}
+			}
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("keys = %v, want %v", keys, tt.wantKeys)
+			}
+
+			stats := store.Stats()
+			if stats.Entries != len(tt.wantKeys) || stats.Bytes != int64(len(tt.wantKeys))*(entryOverhead+1+5) || stats.Evictions != tt.wantEvictions {
+				t.Errorf("Stats() = %+v", stats)
+			}
+		})
+	}
+}
+
+func TestMemoryStore_Stats(t *testing.T) {
+	store := NewMemoryStore(0)
+	defer func() { _ = store.Close() }()
+
+	_ = store.Set("key", &Entry{Value: "value"}, time.Hour)
+	_ = store.Set("key", &Entry{Value: "longer value"}, time.Hour)
+	if got, want := store.Stats(), (MemoryStats{Entries: 1, Bytes: entryOverhead + 3 + 12}); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+
+	_ = store.Delete("key")
+	if got := store.Stats(); got != (MemoryStats{}) {
+		t.Errorf("Stats() = %+v after Delete()", got)
+	}
+
+	_ = store.Set("key", &Entry{Value: "value"}, time.Hour)
+	_ = store.Set("missing", &Entry{NotFound: true}, time.Hour)
+	if got := store.Stats(); got.Entries != 2 || got.NegativeEntries != 1 {
+		t.Errorf("Stats() = %+v with a negative entry", got)
+	}
+
+	_ = store.Set("missing", &Entry{Value: "value"}, time.Hour)
+	if got := store.Stats(); got.NegativeEntries != 0 {
+		t.Errorf("Stats() = %+v after replacing the negative entry", got)
+	}
+
+	_ = store.Set("missing", &Entry{NotFound: true}, time.Hour)
+	_ = store.Clear()
+	if got := store.Stats(); got != (MemoryStats{}) {
+		t.Errorf("Stats() = %+v after Clear()", got)
+	}
+}
+
+type mockBinaryValue struct{}
+
+func (mockBinaryValue) MarshalBinary() ([]byte, error) {
+	return make([]byte, 10), nil
+}
+
+func Test_entrySize(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  int64
+	}{
+		{name: "nil", value: nil, want: entryOverhead + 3},
+		{name: "string", value: "value", want: entryOverhead + 3 + 5},
+		{name: "bytes", value: []byte("value"), want: entryOverhead + 3 + 5},
+		{name: "strings", value: []string{"v1.0.0", "v1.1.0"}, want: entryOverhead + 3 + 12},
+		{name: "binary-marshaler", value: mockBinaryValue{}, want: entryOverhead + 3 + 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := entrySize("key", &Entry{Value: tt.value}); got != tt.want {
+				t.Errorf("entrySize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"strings"
+	"time"
+)
+
+const redisScanCount = 100
+
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore connects to the Redis server at url (e.g. "redis://localhost:6379/0").
+// All keys are prefixed, so that several applications can share a database.
+func NewRedisStore(url, prefix string) (*RedisStore, error) {
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(options)
+
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+
+	return &RedisStore{client: client, prefix: prefix}, nil
+}
+
+func (s *RedisStore) Get(key string) (*Entry, bool, error) {
+	data, err := s.client.Get(context.Background(), s.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	entry, err := decodeEntry(data)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return entry, true, nil
+}
+
+func (s *RedisStore) Set(key string, entry *Entry, ttl time.Duration) error {
+	if ttl <= 0 {
+		// Redis would keep the key forever, whereas the entry is already expired.
+		return s.Delete(key)
+	}
+
+	data, err := encodeEntry(entry)
+	if err != nil {
+		return err
+	}
+
+	return s.client.Set(context.Background(), s.prefix+key, data, ttl).Err()
+}
+
+func (s *RedisStore) Delete(key string) error {
+	return s.client.Del(context.Background(), s.prefix+key).Err()
+}
+
+func (s *RedisStore) Range(fn func(key string, entry *Entry)) error {
+	return s.scan(func(ctx context.Context, keys []string) error {
+		values, err := s.client.MGet(ctx, keys...).Result()
+		if err != nil {
+			return err
+		}
+
+		for i, value := range values {
+			data, ok := value.(string)
+			if !ok {
+				// The key expired in the meantime.
+				continue
+			}
+
+			// Entries that can't be decoded are treated as a miss anyway.
+			if entry, err := decodeEntry([]byte(data)); err == nil {
+				fn(strings.TrimPrefix(keys[i], s.prefix), entry)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *RedisStore) Clear() error {
+	return s.scan(func(ctx context.Context, keys []string) error {
+		return s.client.Unlink(ctx, keys...).Err()
+	})
+}
+
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+func (s *RedisStore) scan(fn func(ctx context.Context, keys []string) error) error {
+	ctx := context.Background()
+	iter := s.client.Scan(ctx, 0, escapeRedisPattern(s.prefix)+"*", redisScanCount).Iterator()
+	keys := make([]string, 0, redisScanCount)
+
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+
+		if len(keys) == redisScanCount {
+			if err := fn(ctx, keys); err != nil {
+				return err
+			}
+
+			keys = keys[:0]
+		}
+	}
+
+	if err := iter.Err(); err != nil {
+		return err
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return fn(ctx, keys)
+}
+
+func escapeRedisPattern(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+	return replacer.Replace(s)
+}
@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func newTestRedisStore(t *testing.T, server *miniredis.Miniredis, prefix string) *RedisStore {
+	t.Helper()
+
+	store, err := NewRedisStore("redis://"+server.Addr(), prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	return store
+}
+
+func TestRedisStore(t *testing.T) {
+	server := miniredis.RunT(t)
+	store := newTestRedisStore(t, server, "test:")
+
+	if _, ok, err := store.Get("key"); ok || err != nil {
+		t.Errorf("Get() = %v, %v for missing key", ok, err)
+	}
+
+	expiration := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := store.Set("key", &Entry{Expiration: expiration, Value: &mockValue{Name: "foo"}}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Entry{Expiration: expiration, Value: &mockValue{Name: "foo"}}
+	if got, ok, err := store.Get("key"); !ok || err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Get() = %+v, %v, %v", got, ok, err)
+	}
+	if !server.Exists("test:key") {
+		t.Error("key isn't prefixed")
+	}
+
+	server.FastForward(time.Minute)
+	if _, ok, _ := store.Get("key"); ok {
+		t.Error("Get() found expired key")
+	}
+
+	_ = store.Set("key", &Entry{}, time.Minute)
+	_ = store.Delete("key")
+	if _, ok, _ := store.Get("key"); ok {
+		t.Error("Get() found deleted key")
+	}
+
+	_ = store.Set("key", &Entry{}, 0)
+	if _, ok, _ := store.Get("key"); ok {
+		t.Error("Get() found key without TTL")
+	}
+}
+
+func TestRedisStore_undecodable(t *testing.T) {
+	server := miniredis.RunT(t)
+	store := newTestRedisStore(t, server, "test:")
+
+	_ = server.Set("test:key", "garbage")
+
+	if _, ok, err := store.Get("key"); ok || err == nil {
+		t.Errorf("Get() = %v, %v for undecodable entry", ok, err)
+	}
+}
+
+func TestRedisStore_RangeClear(t *testing.T) {
+	server := miniredis.RunT(t)
+	store := newTestRedisStore(t, server, "test*:")
+	other := newTestRedisStore(t, server, "other:")
+
+	for i := 0; i < redisScanCount+1; i++ {
+		_ = store.Set(Key("foo", string(rune('a'+i%26)), time.Duration(i).String()), &Entry{}, time.Hour)
+	}
+	_ = store.Set("bar", &Entry{}, time.Hour)
+	_ = other.Set("foo", &Entry{}, time.Hour)
+	_ = server.Set("test*:garbage", "garbage")
+
+	keys := []string{}
+	if err := store.Range(func(key string, _ *Entry) { keys = append(keys, key) }); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(keys)
+	if len(keys) != redisScanCount+2 || keys[0] != "bar" {
+		t.Errorf("Range() returned %d keys, first %q", len(keys), keys[0])
+	}
+
+	if err := store.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if keys := server.Keys(); !reflect.DeepEqual(keys, []string{"other:foo"}) {
+		t.Errorf("keys after Clear() = %v", keys)
+	}
+}
+
+func TestNewRedisStore(t *testing.T) {
+	if _, err := NewRedisStore("invalid://", ""); err == nil {
+		t.Error("no error for invalid URL")
+	}
+
+	server := miniredis.RunT(t)
+	addr := server.Addr()
+	server.Close()
+
+	if _, err := NewRedisStore("redis://"+addr, ""); err == nil {
+		t.Error("no error for unavailable server")
+	}
+}
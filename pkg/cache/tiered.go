@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"errors"
+	"time"
+)
+
+// TieredStore keeps entries of a shared store in a local store for up to localTTL, which saves round trips to the
+// shared store, but delays invalidations by other processes.
+type TieredStore struct {
+	local    Store
+	shared   Store
+	localTTL time.Duration
+}
+
+func NewTieredStore(local, shared Store, localTTL time.Duration) *TieredStore {
+	return &TieredStore{local: local, shared: shared, localTTL: localTTL}
+}
+
+func (s *TieredStore) Get(key string) (*Entry, bool, error) {
+	if entry, ok, err := s.local.Get(key); ok && err == nil {
+		return entry, true, nil
+	}
+
+	entry, ok, err := s.shared.Get(key)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	if err := s.local.Set(key, entry, s.localTTL); err != nil {
+		return nil, false, err
+	}
+
+	return entry, true, nil
+}
+
+func (s *TieredStore) Set(key string, entry *Entry, ttl time.Duration) error {
+	// The local entry is written first, so that it's served even if the shared store is unavailable.
+	if err := s.local.Set(key, entry, min(ttl, s.localTTL)); err != nil {
+		return err
+	}
+
+	return s.shared.Set(key, entry, ttl)
+}
+
+func (s *TieredStore) Delete(key string) error {
+	return errors.Join(s.local.Delete(key), s.shared.Delete(key))
+}
+
+func (s *TieredStore) Range(fn func(key string, entry *Entry)) error {
+	return s.shared.Range(fn)
+}
+
+func (s *TieredStore) Clear() error {
+	return errors.Join(s.local.Clear(), s.shared.Clear())
+}
+
+func (s *TieredStore) Close() error {
+	return errors.Join(s.local.Close(), s.shared.Close())
+}
@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"errors"
+	"github.com/alicebob/miniredis/v2"
+	"testing"
+	"time"
+)
+
+func TestTieredStore(t *testing.T) {
+	server := miniredis.RunT(t)
+	shared := newTestRedisStore(t, server, "test:")
+	local := NewMemoryStore(0)
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	local.now = func() time.Time { return now }
+	store := NewTieredStore(local, shared, 10*time.Second)
+
+	if err := store.Set("key", &Entry{Value: &mockValue{Name: "foo"}}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another process deletes the entry, but the local store keeps serving it for up to the local TTL.
+	other := newTestRedisStore(t, server, "test:")
+	_ = other.Delete("key")
+	if _, ok, _ := store.Get("key"); !ok {
+		t.Error("Get() didn't find the local entry")
+	}
+
+	now = now.Add(10 * time.Second)
+	if _, ok, _ := store.Get("key"); ok {
+		t.Error("Get() found the entry after the local TTL")
+	}
+
+	// Another process sets the entry, which is copied to the local store.
+	_ = other.Set("key", &Entry{Value: &mockValue{Name: "bar"}}, time.Hour)
+	if got, ok, _ := store.Get("key"); !ok || got.Value.(*mockValue).Name != "bar" {
+		t.Errorf("Get() = %+v, %v for shared entry", got, ok)
+	}
+	if _, ok, _ := local.Get("key"); !ok {
+		t.Error("shared entry wasn't copied to the local store")
+	}
+
+	_ = store.Delete("key")
+	if _, ok, _ := store.Get("key"); ok {
+		t.Error("Get() found deleted key")
+	}
+
+	_ = store.Set("key", &Entry{}, time.Hour)
+	_ = store.Clear()
+	if _, ok, _ := store.Get("key"); ok {
+		t.Error("Get() found key after Clear()")
+	}
+}
+
+func TestTieredStore_sharedErrors(t *testing.T) {
+	shared := newMockStore()
+	shared.getErr = errors.New("get error")
+	shared.setErr = errors.New("set error")
+	store := NewTieredStore(NewMemoryStore(0), shared, time.Minute)
+
+	if err := store.Set("key", &Entry{}, time.Hour); err == nil {
+		t.Error("Set() didn't return the error of the shared store")
+	}
+	if _, ok, err := store.Get("key"); !ok || err != nil {
+		t.Errorf("Get() = %v, %v while the shared store fails", ok, err)
+	}
+	if _, _, err := store.Get("other"); err == nil {
+		t.Error("Get() didn't return the error of the shared store")
+	}
+}
@@ -0,0 +1,59 @@
+package gitea
+
+import (
+	"context"
+	"errors"
+	"go.eigsys.de/masquerade/pkg/internal/apiclient"
+	"net/http"
+	"net/url"
+)
+
+type User struct {
+	ID    int64  `json:"id"`
+	Login string `json:"login"`
+}
+
+type Repo struct {
+	ID            int64  `json:"id"`
+	Owner         *User  `json:"owner"`
+	Name          string `json:"name"`
+	FullName      string `json:"full_name"`
+	HTMLURL       string `json:"html_url"`
+	CloneURL      string `json:"clone_url"`
+	Website       string `json:"website"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+type ErrorResponse = apiclient.ErrorResponse
+
+type Client struct {
+	api *apiclient.Client
+}
+
+func NewClient(baseURL string, httpClient *http.Client, token string) (*Client, error) {
+	if baseURL == "" {
+		return nil, errors.New("missing base URL")
+	}
+
+	if token != "" {
+		token = "token " + token
+	}
+
+	api, err := apiclient.New(baseURL, httpClient, "Authorization", token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{api: api}, nil
+}
+
+func (c *Client) GetRepo(ctx context.Context, owner, repo string) (*Repo, *http.Response, error) {
+	data := &Repo{}
+
+	resp, err := c.api.Get(ctx, "/api/v1/repos/"+url.PathEscape(owner)+"/"+url.PathEscape(repo), data)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return data, resp, nil
+}
@@ -0,0 +1,155 @@
+package gitea
+
+import (
+	"context"
+	"go.eigsys.de/masquerade/pkg/internal/apiclient/apiclienttest"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+const theRepoJSON = `{"id":1,"owner":{"id":2,"login":"the-owner"},"name":"the-repo","full_name":"the-owner/the-repo","html_url":"https://gitea.example.com/the-owner/the-repo","clone_url":"https://gitea.example.com/the-owner/the-repo.git","website":"https://the-repo.example.com","default_branch":"main"}`
+
+var theRepo = &Repo{
+	ID:            1,
+	Owner:         &User{ID: 2, Login: "the-owner"},
+	Name:          "the-repo",
+	FullName:      "the-owner/the-repo",
+	HTMLURL:       "https://gitea.example.com/the-owner/the-repo",
+	CloneURL:      "https://gitea.example.com/the-owner/the-repo.git",
+	Website:       "https://the-repo.example.com",
+	DefaultBranch: "main",
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	return apiclienttest.NewServer(t, "/api/v1/repos/", "Authorization", map[string]string{
+		"/api/v1/repos/the-owner/the-repo":         theRepoJSON,
+		"/api/v1/repos/the-owner/transferred-repo": `{"id":3,"owner":{"id":4,"login":"another-owner"},"name":"transferred-repo"}`,
+		"/api/v1/repos/the-owner/invalid-json":     `{`,
+	})
+}
+
+func TestNewClient(t *testing.T) {
+	tests := []struct {
+		name        string
+		baseURL     string
+		wantBaseURL string
+		wantErr     bool
+	}{
+		{
+			name:        "ok",
+			baseURL:     "https://gitea.example.com",
+			wantBaseURL: "https://gitea.example.com",
+		},
+		{
+			name:        "trailing-slash",
+			baseURL:     "https://gitea.example.com/",
+			wantBaseURL: "https://gitea.example.com",
+		},
+		{
+			name:    "missing-base-url",
+			baseURL: "",
+			wantErr: true,
+		},
+		{
+			name:    "missing-scheme",
+			baseURL: "gitea.example.com",
+			wantErr: true,
+		},
+		{
+			name:    "invalid-url",
+			baseURL: "https://gitea.example.com/%zz",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewClient(tt.baseURL, nil, "")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewClient() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && got.api.BaseURL() != tt.wantBaseURL {
+				t.Errorf("NewClient() baseURL = %v, want %v", got.api.BaseURL(), tt.wantBaseURL)
+			}
+		})
+	}
+}
+
+func TestClient_GetRepo(t *testing.T) {
+	server := newTestServer(t)
+	tests := []struct {
+		name           string
+		token          string
+		repo           string
+		want           *Repo
+		wantStatusCode int
+		wantErr        bool
+	}{
+		{
+			name:           "ok",
+			repo:           "the-repo",
+			want:           theRepo,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "ok-with-token",
+			token:          "the-token",
+			repo:           "the-repo",
+			want:           theRepo,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "not-found",
+			repo:           "unknown",
+			wantStatusCode: http.StatusNotFound,
+			wantErr:        true,
+		},
+		{
+			name:           "unauthorized",
+			token:          apiclienttest.InvalidToken,
+			repo:           "the-repo",
+			wantStatusCode: http.StatusUnauthorized,
+			wantErr:        true,
+		},
+		{
+			name:           "invalid-json",
+			repo:           "invalid-json",
+			wantStatusCode: http.StatusOK,
+			wantErr:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewClient(server.URL, server.Client(), tt.token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, resp, err := c.GetRepo(context.Background(), "the-owner", tt.repo)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetRepo() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if resp == nil || resp.StatusCode != tt.wantStatusCode {
+				t.Errorf("GetRepo() resp = %v, wantStatusCode %v", resp, tt.wantStatusCode)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetRepo() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_GetRepo_error(t *testing.T) {
+	c, err := NewClient("http://127.0.0.1:0", nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := c.GetRepo(context.Background(), "the-owner", "the-repo"); err == nil {
+		t.Error("no error")
+	}
+}
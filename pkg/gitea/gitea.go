@@ -0,0 +1,65 @@
+package gitea
+
+import (
+	"context"
+	"go.eigsys.de/masquerade/pkg/internal/apiclient"
+	"go.eigsys.de/masquerade/pkg/repository"
+	"golang.org/x/time/rate"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var repoRegexp = regexp.MustCompile(`^[a-zA-Z0-9-_.]{1,100}$`)
+
+type RepositoriesService interface {
+	GetRepo(ctx context.Context, owner, repo string) (*Repo, *http.Response, error)
+}
+
+type Gitea struct {
+	repositoriesService RepositoriesService
+	limiter             *rate.Limiter
+	owner               string
+}
+
+func New(repositoriesService RepositoriesService, limiter *rate.Limiter, owner string) *Gitea {
+	return &Gitea{
+		repositoriesService: repositoriesService,
+		limiter:             limiter,
+		owner:               owner,
+	}
+}
+
+func (g *Gitea) Type() string {
+	return "git"
+}
+
+func (g *Gitea) isValidRepo(repo string) bool {
+	return repoRegexp.MatchString(repo)
+}
+
+func (g *Gitea) isOwnedRepo(data *Repo) bool {
+	return data.Owner != nil && strings.EqualFold(data.Owner.Login, g.owner)
+}
+
+func (g *Gitea) Fetch(ctx context.Context, repo string) (repository.Repository, error) {
+	if !g.isValidRepo(repo) {
+		return nil, repository.ErrInvalidName
+	}
+
+	if err := g.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	data, resp, err := g.repositoriesService.GetRepo(ctx, g.owner, repo)
+	if err != nil {
+		return nil, apiclient.ClassifyError(resp, err)
+	}
+
+	// Gitea follows redirects of transferred repositories, which must not leave the owner's scope.
+	if !g.isOwnedRepo(data) {
+		return nil, repository.ErrNotFound
+	}
+
+	return &Repository{repo: data}, nil
+}
@@ -0,0 +1,225 @@
+package gitea
+
+import (
+	"context"
+	"errors"
+	"go.eigsys.de/masquerade/pkg/internal/apiclient/apiclienttest"
+	"go.eigsys.de/masquerade/pkg/repository"
+	"golang.org/x/time/rate"
+	"reflect"
+	"testing"
+)
+
+func TestGitea_Type(t *testing.T) {
+	g := &Gitea{}
+
+	if g.Type() != "git" {
+		t.Errorf("wrong type")
+	}
+}
+
+func TestGitea_isValidRepo(t *testing.T) {
+	type args struct {
+		repo string
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{
+			name: "valid-1-character",
+			args: args{repo: "a"},
+			want: true,
+		},
+		{
+			name: "valid-special-characters",
+			args: args{repo: "-_."},
+			want: true,
+		},
+		{
+			name: "invalid-0-characters",
+			args: args{repo: ""},
+			want: false,
+		},
+		{
+			name: "invalid-character",
+			args: args{repo: "/"},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Gitea{}
+			if got := g.isValidRepo(tt.args.repo); got != tt.want {
+				t.Errorf("isValidRepo() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitea_Fetch(t *testing.T) {
+	server := newTestServer(t)
+	client, err := NewClient(server.URL, server.Client(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unauthorizedClient, err := NewClient(server.URL, server.Client(), apiclienttest.InvalidToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type fields struct {
+		repositoriesService RepositoriesService
+		limiter             *rate.Limiter
+		owner               string
+	}
+	type args struct {
+		ctx  context.Context
+		repo string
+	}
+	tests := []struct {
+		name          string
+		fields        fields
+		args          args
+		want          repository.Repository
+		wantErr       bool
+		wantErrResult error
+	}{
+		{
+			name: "ok",
+			fields: fields{
+				repositoriesService: client,
+				limiter:             rate.NewLimiter(rate.Inf, 0),
+				owner:               "the-owner",
+			},
+			args: args{
+				ctx:  context.Background(),
+				repo: "the-repo",
+			},
+			want: &Repository{repo: theRepo},
+		},
+		{
+			name: "owner-case-insensitive",
+			fields: fields{
+				repositoriesService: client,
+				limiter:             rate.NewLimiter(rate.Inf, 0),
+				owner:               "The-Owner",
+			},
+			args: args{
+				ctx:  context.Background(),
+				repo: "the-repo",
+			},
+			want: &Repository{repo: theRepo},
+		},
+		{
+			name: "invalid-repo",
+			args: args{
+				repo: "the/repo",
+			},
+			wantErr: true,
+		},
+		{
+			name: "limiter-error",
+			fields: fields{
+				limiter: rate.NewLimiter(0, 0),
+			},
+			args: args{
+				ctx:  context.Background(),
+				repo: "the-repo",
+			},
+			wantErr: true,
+		},
+		{
+			name: "repository-not-found",
+			fields: fields{
+				repositoriesService: client,
+				limiter:             rate.NewLimiter(rate.Inf, 0),
+				owner:               "the-owner",
+			},
+			args: args{
+				ctx:  context.Background(),
+				repo: "unknown",
+			},
+			wantErr:       true,
+			wantErrResult: repository.ErrNotFound,
+		},
+		{
+			name: "repository-of-another-owner",
+			fields: fields{
+				repositoriesService: client,
+				limiter:             rate.NewLimiter(rate.Inf, 0),
+				owner:               "the-owner",
+			},
+			args: args{
+				ctx:  context.Background(),
+				repo: "transferred-repo",
+			},
+			wantErr:       true,
+			wantErrResult: repository.ErrNotFound,
+		},
+		{
+			name: "unauthorized",
+			fields: fields{
+				repositoriesService: unauthorizedClient,
+				limiter:             rate.NewLimiter(rate.Inf, 0),
+				owner:               "the-owner",
+			},
+			args: args{
+				ctx:  context.Background(),
+				repo: "the-repo",
+			},
+			wantErr:       true,
+			wantErrResult: repository.ErrForbidden,
+		},
+		{
+			name: "generic-error",
+			fields: fields{
+				repositoriesService: client,
+				limiter:             rate.NewLimiter(rate.Inf, 0),
+				owner:               "the-owner",
+			},
+			args: args{
+				ctx:  context.Background(),
+				repo: "invalid-json",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Gitea{
+				repositoriesService: tt.fields.repositoriesService,
+				limiter:             tt.fields.limiter,
+				owner:               tt.fields.owner,
+			}
+			got, err := g.Fetch(tt.args.ctx, tt.args.repo)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Fetch() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErrResult != nil && !errors.Is(err, tt.wantErrResult) {
+				t.Errorf("Fetch() error = %v, wantErrResult %v", err, tt.wantErrResult)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Fetch() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	repositoriesService := &Client{}
+	limiter := rate.NewLimiter(0, 0)
+	owner := "the-owner"
+	g := New(repositoriesService, limiter, owner)
+	want := &Gitea{
+		repositoriesService: repositoriesService,
+		limiter:             limiter,
+		owner:               owner,
+	}
+
+	if !reflect.DeepEqual(g, want) {
+		t.Errorf("unexpected result")
+	}
+}
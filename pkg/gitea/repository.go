@@ -0,0 +1,57 @@
+package gitea
+
+import (
+	"encoding/json"
+	"go.eigsys.de/masquerade/pkg/repository"
+)
+
+const (
+	sourceDirectoryPath = "src/branch"
+	sourceFilePath      = "src/branch"
+)
+
+type Repository struct {
+	repo *Repo
+}
+
+type repositoryData struct {
+	Repo *Repo `json:"repo"`
+}
+
+func (r *Repository) MarshalBinary() ([]byte, error) {
+	return json.Marshal(&repositoryData{Repo: r.repo})
+}
+
+func (r *Repository) UnmarshalBinary(data []byte) error {
+	repositoryData := &repositoryData{}
+	if err := json.Unmarshal(data, repositoryData); err != nil {
+		return err
+	}
+
+	r.repo = repositoryData.Repo
+
+	return nil
+}
+
+func (r *Repository) GetRepoRoot() string {
+	if r.repo == nil {
+		return ""
+	}
+	return r.repo.CloneURL
+}
+
+func (r *Repository) GetProjectWebsiteOrFallback(fallback string) string {
+	if r.repo == nil || r.repo.Website == "" {
+		return fallback
+	}
+
+	return r.repo.Website
+}
+
+func (r *Repository) GetSourceURLs(dir string) repository.SourceURLs {
+	if r.repo == nil {
+		return repository.SourceURLs{}
+	}
+
+	return repository.NewSourceURLs(r.repo.HTMLURL, r.repo.DefaultBranch, dir, sourceDirectoryPath, sourceFilePath)
+}
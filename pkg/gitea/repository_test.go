@@ -0,0 +1,136 @@
+package gitea
+
+import (
+	"go.eigsys.de/masquerade/pkg/repository"
+	"reflect"
+	"testing"
+)
+
+func TestRepository_GetRepoRoot(t *testing.T) {
+	type fields struct {
+		repo *Repo
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		want   string
+	}{
+		{
+			name:   "everything-given",
+			fields: fields{repo: theRepo},
+			want:   "https://gitea.example.com/the-owner/the-repo.git",
+		},
+		{
+			name: "repo-nil",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				repo: tt.fields.repo,
+			}
+			if got := r.GetRepoRoot(); got != tt.want {
+				t.Errorf("GetRepoRoot() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_GetProjectWebsiteOrFallback(t *testing.T) {
+	type fields struct {
+		repo *Repo
+	}
+	type args struct {
+		fallback string
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		args   args
+		want   string
+	}{
+		{
+			name:   "everything-given",
+			fields: fields{repo: &Repo{Website: "the-website"}},
+			args:   args{fallback: "the-fallback"},
+			want:   "the-website",
+		},
+		{
+			name: "repo-nil",
+			args: args{fallback: "the-fallback"},
+			want: "the-fallback",
+		},
+		{
+			name:   "website-empty",
+			fields: fields{repo: &Repo{}},
+			args:   args{fallback: "the-fallback"},
+			want:   "the-fallback",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				repo: tt.fields.repo,
+			}
+			if got := r.GetProjectWebsiteOrFallback(tt.args.fallback); got != tt.want {
+				t.Errorf("GetProjectWebsiteOrFallback() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_GetSourceURLs(t *testing.T) {
+	r := &Repository{repo: &Repo{HTMLURL: "https://gitea.example.com/owner/repo", DefaultBranch: "main"}}
+	want := repository.SourceURLs{
+		Home:      "https://gitea.example.com/owner/repo",
+		Directory: "https://gitea.example.com/owner/repo/src/branch/main/tools{/dir}",
+		File:      "https://gitea.example.com/owner/repo/src/branch/main/tools{/dir}/{file}#L{line}",
+	}
+
+	if got := r.GetSourceURLs("tools"); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetSourceURLs() = %v, want %v", got, want)
+	}
+
+	if got := (&Repository{}).GetSourceURLs("tools"); !reflect.DeepEqual(got, repository.SourceURLs{}) {
+		t.Errorf("GetSourceURLs() = %v, want empty", got)
+	}
+}
+
+func TestRepository_MarshalBinary(t *testing.T) {
+	tests := []struct {
+		name string
+		r    *Repository
+	}{
+		{
+			name: "everything-given",
+			r:    &Repository{repo: &Repo{HTMLURL: "https://gitea.example.com/owner/repo", Website: "https://example.com", DefaultBranch: "main"}},
+		},
+		{
+			name: "repo-nil",
+			r:    &Repository{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.r.MarshalBinary()
+			if err != nil {
+				t.Fatalf("MarshalBinary() error = %v", err)
+			}
+
+			got := &Repository{}
+			if err := got.UnmarshalBinary(data); err != nil {
+				t.Fatalf("UnmarshalBinary() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.r) {
+				t.Errorf("UnmarshalBinary() got = %+v, want %+v", got, tt.r)
+			}
+		})
+	}
+}
+
+func TestRepository_UnmarshalBinary_invalid(t *testing.T) {
+	if err := (&Repository{}).UnmarshalBinary([]byte("{")); err == nil {
+		t.Error("UnmarshalBinary() no error")
+	}
+}
@@ -0,0 +1,238 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	appJWTLifetime          = 9 * time.Minute
+	appJWTClockDrift        = 1 * time.Minute
+	installationTokenMargin = 1 * time.Minute
+)
+
+// TokenTransport authenticates requests to the API host of baseURL with a token. Requests to other hosts, e.g.
+// archive downloads redirected to codeload.github.com, are sent without it.
+type TokenTransport struct {
+	base    http.RoundTripper
+	apiHost string
+	token   string
+}
+
+func NewTokenTransport(base http.RoundTripper, baseURL, token string) (*TokenTransport, error) {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	apiHost, err := parseHost(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenTransport{base: base, apiHost: apiHost, token: token}, nil
+}
+
+func (t *TokenTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.URL.Host != t.apiHost {
+		return t.base.RoundTrip(request)
+	}
+
+	return t.base.RoundTrip(withAuthorization(request, "Bearer "+t.token))
+}
+
+func (t *TokenTransport) String() string {
+	return "TokenTransport{token: [redacted]}"
+}
+
+type installationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AppTransport authenticates requests to the API host of baseURL as a GitHub App installation, like TokenTransport.
+type AppTransport struct {
+	base           http.RoundTripper
+	baseURL        string
+	apiHost        string
+	appID          int64
+	installationID int64
+	privateKey     *rsa.PrivateKey
+	now            func() time.Time
+
+	mu    sync.Mutex
+	token *installationToken
+}
+
+func NewAppTransport(base http.RoundTripper, baseURL string, appID, installationID int64, privateKeyPEM []byte) (*AppTransport, error) {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	apiHost, err := parseHost(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := parsePrivateKey(privateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AppTransport{
+		base:           base,
+		baseURL:        strings.TrimSuffix(baseURL, "/"),
+		apiHost:        apiHost,
+		appID:          appID,
+		installationID: installationID,
+		privateKey:     privateKey,
+		now:            time.Now,
+	}, nil
+}
+
+func (t *AppTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.URL.Host != t.apiHost {
+		return t.base.RoundTrip(request)
+	}
+
+	token, err := t.installationToken(request.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	return t.base.RoundTrip(withAuthorization(request, "token "+token))
+}
+
+func (t *AppTransport) String() string {
+	return fmt.Sprintf("AppTransport{appID: %d, installationID: %d, token: [redacted]}", t.appID, t.installationID)
+}
+
+func (t *AppTransport) installationToken(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token != nil && t.now().Add(installationTokenMargin).Before(t.token.ExpiresAt) {
+		return t.token.Token, nil
+	}
+
+	jwt, err := t.appJWT()
+	if err != nil {
+		return "", err
+	}
+
+	endpoint := fmt.Sprintf("%s/app/installations/%d/access_tokens", t.baseURL, t.installationID)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+
+	request.Header.Set("Accept", "application/vnd.github+json")
+	request.Header.Set("Authorization", "Bearer "+jwt)
+
+	response, err := t.base.RoundTrip(request)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	if response.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("creating installation token: unexpected status code %d", response.StatusCode)
+	}
+
+	token := &installationToken{}
+	if err := json.NewDecoder(response.Body).Decode(token); err != nil {
+		return "", fmt.Errorf("creating installation token: %w", err)
+	}
+
+	if token.Token == "" {
+		return "", errors.New("creating installation token: empty token")
+	}
+
+	t.token = token
+
+	return token.Token, nil
+}
+
+func (t *AppTransport) appJWT() (string, error) {
+	now := t.now()
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := json.Marshal(map[string]int64{
+		"iat": now.Add(-appJWTClockDrift).Unix(),
+		"exp": now.Add(appJWTLifetime).Unix(),
+		"iss": t.appID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, t.privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func parsePrivateKey(privateKeyPEM []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return nil, errors.New("invalid private key: no PEM block found")
+	}
+
+	if privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return privateKey, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.New("invalid private key: neither PKCS #1 nor PKCS #8")
+	}
+
+	privateKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("invalid private key: not an RSA key")
+	}
+
+	return privateKey, nil
+}
+
+func parseHost(baseURL string) (string, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return "", err
+	}
+
+	if parsed.Host == "" {
+		return "", fmt.Errorf("invalid API base URL %q", baseURL)
+	}
+
+	return parsed.Host, nil
+}
+
+func withAuthorization(request *http.Request, value string) *http.Request {
+	request = request.Clone(request.Context())
+	request.Header.Set("Authorization", value)
+
+	return request
+}
@@ -0,0 +1,301 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"golang.org/x/time/rate"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestPrivateKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	t.Helper()
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return privateKey, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+}
+
+func verifyTestJWT(t *testing.T, publicKey *rsa.PublicKey, jwt string) map[string]int64 {
+	t.Helper()
+
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Fatalf("invalid JWT %q", jwt)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature); err != nil {
+		t.Fatalf("invalid JWT signature: %s", err)
+	}
+
+	rawClaims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := map[string]int64{}
+	if err := json.Unmarshal(rawClaims, &claims); err != nil {
+		t.Fatal(err)
+	}
+
+	return claims
+}
+
+func TestTokenTransport_RoundTrip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		_, _ = fmt.Fprint(response, request.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		baseURL string
+		want    string
+	}{
+		{name: "api-host", baseURL: server.URL + "/api/v3/", want: "Bearer the-token"},
+		{name: "other-host", baseURL: "https://api.github.com/", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport, err := NewTokenTransport(nil, tt.baseURL, "the-token")
+			if err != nil {
+				t.Fatal(err)
+			}
+			request := httptest.NewRequest(http.MethodGet, server.URL, nil)
+			request.RequestURI = ""
+
+			response, err := transport.RoundTrip(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = response.Body.Close() }()
+
+			body, err := io.ReadAll(response.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != tt.want {
+				t.Errorf("wrong authorization header %q, want %q", body, tt.want)
+			}
+			if request.Header.Get("Authorization") != "" {
+				t.Error("original request has been modified")
+			}
+		})
+	}
+}
+
+func TestNewTokenTransport_invalidBaseURL(t *testing.T) {
+	if _, err := NewTokenTransport(nil, "api.github.com", "the-token"); err == nil {
+		t.Error("no error")
+	}
+}
+
+func TestTokenTransport_String(t *testing.T) {
+	transport, err := NewTokenTransport(nil, "https://api.github.com/", "the-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{"%s", "%v", "%+v"} {
+		if strings.Contains(fmt.Sprintf(format, transport), "the-token") {
+			t.Errorf("token leaked using %q", format)
+		}
+	}
+}
+
+func TestAppTransport(t *testing.T) {
+	privateKey, privateKeyPEM := newTestPrivateKey(t)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	var tokenRequests atomic.Int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/app/installations/42/access_tokens", func(response http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodPost {
+			t.Errorf("unexpected method %q", request.Method)
+		}
+
+		claims := verifyTestJWT(t, &privateKey.PublicKey, strings.TrimPrefix(request.Header.Get("Authorization"), "Bearer "))
+		if claims["iss"] != 1 || claims["iat"] >= now.Unix() || claims["exp"] <= now.Unix() {
+			t.Errorf("unexpected claims %v", claims)
+		}
+
+		count := tokenRequests.Add(1)
+		response.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprintf(response, `{"token":"installation-token-%d","expires_at":%q}`, count, now.Add(time.Hour).Format(time.RFC3339))
+	})
+	mux.HandleFunc("/codeload/the-owner/private-repo", func(response http.ResponseWriter, request *http.Request) {
+		_, _ = fmt.Fprint(response, request.Header.Get("Authorization"))
+	})
+	mux.HandleFunc("/api/v3/repos/the-owner/private-repo", func(response http.ResponseWriter, request *http.Request) {
+		if !strings.HasPrefix(request.Header.Get("Authorization"), "token installation-token-") {
+			http.Error(response, `{"message":"Not Found"}`, http.StatusNotFound)
+			return
+		}
+
+		_, _ = fmt.Fprint(response, `{"name":"private-repo","private":true,"html_url":"https://ghes.example.com/the-owner/private-repo"}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	httpClient := &http.Client{}
+	client, err := NewClient(server.URL, "", httpClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport, err := NewAppTransport(nil, client.BaseURL.String(), 1, 42, privateKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport.now = func() time.Time { return now }
+	httpClient.Transport = transport
+	g := New(client.Repositories, rate.NewLimiter(rate.Inf, 0), "the-owner")
+
+	for i := 0; i < 2; i++ {
+		got, err := g.Fetch(context.Background(), "private-repo")
+		if err != nil {
+			t.Fatalf("Fetch() error = %v", err)
+		}
+		if got.GetRepoRoot() != "https://ghes.example.com/the-owner/private-repo" {
+			t.Errorf("GetRepoRoot() = %v", got.GetRepoRoot())
+		}
+	}
+
+	if tokenRequests.Load() != 1 {
+		t.Errorf("installation token requested %d times, want 1", tokenRequests.Load())
+	}
+
+	now = now.Add(59*time.Minute + 30*time.Second)
+	if _, err := g.Fetch(context.Background(), "private-repo"); err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+
+	if tokenRequests.Load() != 2 {
+		t.Errorf("installation token requested %d times, want 2", tokenRequests.Load())
+	}
+
+	if strings.Contains(fmt.Sprint(transport), "installation-token") {
+		t.Error("token leaked")
+	}
+
+	// Archives are downloaded from another host, e.g. codeload.github.com, which must not get the token.
+	codeloadURL := strings.Replace(server.URL, "127.0.0.1", "localhost", 1) + "/codeload/the-owner/private-repo"
+	response, err := httpClient.Get(codeloadURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	if body, _ := io.ReadAll(response.Body); len(body) != 0 {
+		t.Errorf("token sent to another host: %q", body)
+	}
+}
+
+func TestAppTransport_RoundTrip_error(t *testing.T) {
+	_, privateKeyPEM := newTestPrivateKey(t)
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{
+			name: "unexpected-status-code",
+			handler: func(response http.ResponseWriter, _ *http.Request) {
+				http.Error(response, `{"message":"A JSON web token could not be decoded"}`, http.StatusUnauthorized)
+			},
+		},
+		{
+			name: "invalid-json",
+			handler: func(response http.ResponseWriter, _ *http.Request) {
+				response.WriteHeader(http.StatusCreated)
+				_, _ = fmt.Fprint(response, `{`)
+			},
+		},
+		{
+			name: "empty-token",
+			handler: func(response http.ResponseWriter, _ *http.Request) {
+				response.WriteHeader(http.StatusCreated)
+				_, _ = fmt.Fprint(response, `{}`)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+			transport, err := NewAppTransport(nil, server.URL, 1, 42, privateKeyPEM)
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := &http.Client{Transport: transport}
+
+			if _, err := client.Get(server.URL); err == nil {
+				t.Error("no error")
+			}
+		})
+	}
+}
+
+func Test_parsePrivateKey(t *testing.T) {
+	privateKey, pkcs1PEM := newTestPrivateKey(t)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		pem     []byte
+		wantErr bool
+	}{
+		{
+			name: "pkcs1",
+			pem:  pkcs1PEM,
+		},
+		{
+			name: "pkcs8",
+			pem:  pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}),
+		},
+		{
+			name:    "no-pem",
+			pem:     []byte("the-key"),
+			wantErr: true,
+		},
+		{
+			name:    "invalid-key",
+			pem:     pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("the-key")}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePrivateKey(tt.pem)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parsePrivateKey() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && !got.Equal(privateKey) {
+				t.Error("wrong key")
+			}
+		})
+	}
+}
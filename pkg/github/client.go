@@ -0,0 +1,19 @@
+package github
+
+import (
+	"github.com/google/go-github/v52/github"
+	"net/http"
+	"strings"
+)
+
+func NewClient(baseURL, uploadURL string, httpClient *http.Client) (*github.Client, error) {
+	if baseURL == "" {
+		return github.NewClient(httpClient), nil
+	}
+
+	if uploadURL == "" {
+		uploadURL = strings.TrimSuffix(strings.TrimSuffix(baseURL, "/"), "/api/v3")
+	}
+
+	return github.NewEnterpriseClient(baseURL, uploadURL, httpClient)
+}
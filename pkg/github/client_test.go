@@ -0,0 +1,95 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"golang.org/x/time/rate"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newEnterpriseTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("/api/v3/repos/the-owner/the-repo", func(response http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprintf(response, `{"name":"the-repo","html_url":"%[1]s/the-owner/the-repo","clone_url":"%[1]s/the-owner/the-repo.git"}`, server.URL)
+	})
+
+	return server
+}
+
+func TestNewClient(t *testing.T) {
+	tests := []struct {
+		name          string
+		baseURL       string
+		uploadURL     string
+		wantBaseURL   string
+		wantUploadURL string
+		wantErr       bool
+	}{
+		{
+			name:          "github-com",
+			wantBaseURL:   "https://api.github.com/",
+			wantUploadURL: "https://uploads.github.com/",
+		},
+		{
+			name:          "enterprise",
+			baseURL:       "https://ghes.example.com",
+			uploadURL:     "https://ghes-uploads.example.com",
+			wantBaseURL:   "https://ghes.example.com/api/v3/",
+			wantUploadURL: "https://ghes-uploads.example.com/api/uploads/",
+		},
+		{
+			name:          "enterprise-without-upload-url",
+			baseURL:       "https://ghes.example.com/api/v3/",
+			wantBaseURL:   "https://ghes.example.com/api/v3/",
+			wantUploadURL: "https://ghes.example.com/api/uploads/",
+		},
+		{
+			name:    "invalid-base-url",
+			baseURL: "https://ghes.example.com/%zz",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewClient(tt.baseURL, tt.uploadURL, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewClient() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if got.BaseURL.String() != tt.wantBaseURL {
+				t.Errorf("NewClient() BaseURL = %v, want %v", got.BaseURL, tt.wantBaseURL)
+			}
+			if got.UploadURL.String() != tt.wantUploadURL {
+				t.Errorf("NewClient() UploadURL = %v, want %v", got.UploadURL, tt.wantUploadURL)
+			}
+		})
+	}
+}
+
+func TestNewClient_enterpriseFetch(t *testing.T) {
+	server := newEnterpriseTestServer(t)
+	client, err := NewClient(server.URL, "", server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := New(client.Repositories, rate.NewLimiter(rate.Inf, 0), "the-owner")
+
+	got, err := g.Fetch(context.Background(), "the-repo")
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+
+	if want := server.URL + "/the-owner/the-repo"; got.GetRepoRoot() != want {
+		t.Errorf("GetRepoRoot() = %v, want %v", got.GetRepoRoot(), want)
+	}
+}
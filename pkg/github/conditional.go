@@ -0,0 +1,61 @@
+package github
+
+import (
+	"context"
+	"go.eigsys.de/masquerade/pkg/repository"
+	"net/http"
+)
+
+type validatorsKey struct{}
+
+type validators struct {
+	etag         string
+	lastModified string
+}
+
+func withValidators(ctx context.Context, etag, lastModified string) context.Context {
+	return context.WithValue(ctx, validatorsKey{}, validators{etag: etag, lastModified: lastModified})
+}
+
+// ConditionalTransport turns requests into conditional requests if the context carries the validators of a previous response.
+type ConditionalTransport struct {
+	base http.RoundTripper
+}
+
+func NewConditionalTransport(base http.RoundTripper) *ConditionalTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &ConditionalTransport{base: base}
+}
+
+func (t *ConditionalTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	v, ok := request.Context().Value(validatorsKey{}).(validators)
+	if !ok || request.Method != http.MethodGet {
+		return t.base.RoundTrip(request)
+	}
+
+	request = request.Clone(request.Context())
+
+	if v.etag != "" {
+		request.Header.Set("If-None-Match", v.etag)
+	}
+
+	if v.lastModified != "" {
+		request.Header.Set("If-Modified-Since", v.lastModified)
+	}
+
+	return t.base.RoundTrip(request)
+}
+
+// Revalidate fetches the repository, unless GitHub confirms that the previous one is still up to date.
+// Unchanged repositories don't count against the rate limit.
+func (g *GitHub) Revalidate(ctx context.Context, repo string, previous repository.Repository) (repository.Repository, error) {
+	r, ok := previous.(*Repository)
+	if !ok || r == nil || (r.etag == "" && r.lastModified == "") {
+		return g.Fetch(ctx, repo)
+	}
+
+	return g.fetch(ctx, repo, r)
+}
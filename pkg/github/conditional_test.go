@@ -0,0 +1,116 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"golang.org/x/time/rate"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type conditionalTestServer struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	etag          string
+	fullResponses int
+	notModified   int
+}
+
+func newConditionalTestServer(t *testing.T) *conditionalTestServer {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	server := &conditionalTestServer{Server: httptest.NewServer(mux), etag: `"v1"`}
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("/api/v3/repos/the-owner/the-repo", func(response http.ResponseWriter, request *http.Request) {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+
+		if request.Header.Get("If-None-Match") == server.etag {
+			server.notModified++
+			response.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		server.fullResponses++
+		response.Header().Set("ETag", server.etag)
+		_, _ = fmt.Fprintf(response, `{"name":"the-repo","html_url":"%s/the-owner/the-repo","homepage":%q}`, server.URL, server.etag)
+	})
+
+	return server
+}
+
+func (s *conditionalTestServer) setETag(etag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.etag = etag
+}
+
+func (s *conditionalTestServer) counts() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.fullResponses, s.notModified
+}
+
+func TestGitHub_Revalidate(t *testing.T) {
+	server := newConditionalTestServer(t)
+	client, err := NewClient(server.URL, "", &http.Client{Transport: NewConditionalTransport(nil)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := New(client.Repositories, rate.NewLimiter(rate.Inf, 0), "the-owner")
+
+	first, err := g.Fetch(context.Background(), "the-repo")
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+
+	second, err := g.Revalidate(context.Background(), "the-repo", first)
+	if err != nil {
+		t.Fatalf("Revalidate() error = %v", err)
+	}
+	if second != first {
+		t.Error("Revalidate() didn't return the previous repository")
+	}
+	if fullResponses, notModified := server.counts(); fullResponses != 1 || notModified != 1 {
+		t.Errorf("full responses = %d, not modified = %d after revalidation", fullResponses, notModified)
+	}
+
+	server.setETag(`"v2"`)
+
+	third, err := g.Revalidate(context.Background(), "the-repo", second)
+	if err != nil {
+		t.Fatalf("Revalidate() error = %v", err)
+	}
+	if got := third.GetProjectWebsiteOrFallback(""); got != `"v2"` {
+		t.Errorf("Revalidate() returned %q instead of the changed repository", got)
+	}
+	if fullResponses, notModified := server.counts(); fullResponses != 2 || notModified != 1 {
+		t.Errorf("full responses = %d, not modified = %d after change", fullResponses, notModified)
+	}
+}
+
+func TestGitHub_Revalidate_withoutValidators(t *testing.T) {
+	server := newConditionalTestServer(t)
+	client, err := NewClient(server.URL, "", &http.Client{Transport: NewConditionalTransport(nil)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := New(client.Repositories, rate.NewLimiter(rate.Inf, 0), "the-owner")
+
+	for _, previous := range []*Repository{nil, {}} {
+		if _, err := g.Revalidate(context.Background(), "the-repo", previous); err != nil {
+			t.Fatalf("Revalidate() error = %v", err)
+		}
+	}
+
+	if fullResponses, notModified := server.counts(); fullResponses != 2 || notModified != 0 {
+		t.Errorf("full responses = %d, not modified = %d", fullResponses, notModified)
+	}
+}
@@ -0,0 +1,36 @@
+package github
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/go-github/v52/github"
+	"go.eigsys.de/masquerade/pkg/internal/apiclient"
+	"go.eigsys.de/masquerade/pkg/repository"
+	"net/http"
+	"time"
+)
+
+func classifyError(resp *github.Response, err error) error {
+	var rateLimitErr *github.RateLimitError
+	var abuseRateLimitErr *github.AbuseRateLimitError
+
+	switch {
+	case errors.As(err, &rateLimitErr):
+		return &repository.RetryAfterError{
+			Err:        fmt.Errorf("%w: %w", repository.ErrRateLimited, err),
+			RetryAfter: time.Until(rateLimitErr.Rate.Reset.Time),
+		}
+	case errors.As(err, &abuseRateLimitErr):
+		return &repository.RetryAfterError{
+			Err:        fmt.Errorf("%w: %w", repository.ErrRateLimited, err),
+			RetryAfter: abuseRateLimitErr.GetRetryAfter(),
+		}
+	}
+
+	var httpResp *http.Response
+	if resp != nil {
+		httpResp = resp.Response
+	}
+
+	return apiclient.ClassifyError(httpResp, err)
+}
@@ -0,0 +1,90 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"github.com/google/go-github/v52/github"
+	"go.eigsys.de/masquerade/pkg/repository"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func Test_classifyError(t *testing.T) {
+	genericError := errors.New("generic error")
+	retryAfter := 30 * time.Second
+	tests := []struct {
+		name           string
+		resp           *github.Response
+		err            error
+		wantErrResult  error
+		wantRetryAfter time.Duration
+	}{
+		{
+			name:          "not-found",
+			resp:          &github.Response{Response: &http.Response{StatusCode: http.StatusNotFound}},
+			err:           genericError,
+			wantErrResult: repository.ErrNotFound,
+		},
+		{
+			name:          "unauthorized",
+			resp:          &github.Response{Response: &http.Response{StatusCode: http.StatusUnauthorized}},
+			err:           genericError,
+			wantErrResult: repository.ErrForbidden,
+		},
+		{
+			name:           "abuse-rate-limit",
+			resp:           &github.Response{Response: &http.Response{StatusCode: http.StatusForbidden}},
+			err:            &github.AbuseRateLimitError{Response: &http.Response{StatusCode: http.StatusForbidden, Request: &http.Request{URL: &url.URL{}}}, RetryAfter: &retryAfter},
+			wantErrResult:  repository.ErrRateLimited,
+			wantRetryAfter: retryAfter,
+		},
+		{
+			name:           "too-many-requests",
+			resp:           &github.Response{Response: &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"30"}}}},
+			err:            genericError,
+			wantErrResult:  repository.ErrRateLimited,
+			wantRetryAfter: retryAfter,
+		},
+		{
+			name:          "service-unavailable",
+			resp:          &github.Response{Response: &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}}},
+			err:           genericError,
+			wantErrResult: repository.ErrUnavailable,
+		},
+		{
+			name:          "no-response",
+			err:           genericError,
+			wantErrResult: repository.ErrUnavailable,
+		},
+		{
+			name:          "canceled",
+			err:           context.Canceled,
+			wantErrResult: context.Canceled,
+		},
+		{
+			name:          "unprocessable-entity",
+			resp:          &github.Response{Response: &http.Response{StatusCode: http.StatusUnprocessableEntity}},
+			err:           genericError,
+			wantErrResult: genericError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyError(tt.resp, tt.err)
+			if !errors.Is(err, tt.wantErrResult) {
+				t.Errorf("classifyError() error = %v, wantErrResult %v", err, tt.wantErrResult)
+			}
+
+			var retryAfterErr *repository.RetryAfterError
+			var gotRetryAfter time.Duration
+			if errors.As(err, &retryAfterErr) {
+				gotRetryAfter = retryAfterErr.RetryAfter
+			}
+			if gotRetryAfter != tt.wantRetryAfter {
+				t.Errorf("classifyError() retry after = %v, want %v", gotRetryAfter, tt.wantRetryAfter)
+			}
+		})
+	}
+}
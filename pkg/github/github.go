@@ -3,31 +3,82 @@ package github
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/google/go-github/v52/github"
+	"go.eigsys.de/masquerade/pkg/importpath"
 	"go.eigsys.de/masquerade/pkg/repository"
+	"golang.org/x/mod/modfile"
 	"golang.org/x/time/rate"
+	"log"
 	"net/http"
+	"net/url"
+	"path"
 	"regexp"
+	"sort"
+	"strings"
 )
 
 var repoRegexp = regexp.MustCompile(`^[a-zA-Z0-9-_.]{1,32}$`)
 
 type RepositoriesService interface {
 	Get(ctx context.Context, owner, repo string) (*github.Repository, *github.Response, error)
+	GetContents(ctx context.Context, owner, repo, path string, opts *github.RepositoryContentGetOptions) (*github.RepositoryContent, []*github.RepositoryContent, *github.Response, error)
+	GetBranch(ctx context.Context, owner, repo, branch string, followRedirects bool) (*github.Branch, *github.Response, error)
+	ListTags(ctx context.Context, owner, repo string, opts *github.ListOptions) ([]*github.RepositoryTag, *github.Response, error)
+	GetCommit(ctx context.Context, owner, repo, sha string, opts *github.ListOptions) (*github.RepositoryCommit, *github.Response, error)
+	ListByOrg(ctx context.Context, org string, opts *github.RepositoryListByOrgOptions) ([]*github.Repository, *github.Response, error)
+	List(ctx context.Context, user string, opts *github.RepositoryListOptions) ([]*github.Repository, *github.Response, error)
+	GetArchiveLink(ctx context.Context, owner, repo string, archiveformat github.ArchiveFormat, opts *github.RepositoryContentGetOptions, followRedirects bool) (*url.URL, *github.Response, error)
+}
+
+type GitService interface {
+	GetTree(ctx context.Context, owner, repo, sha string, recursive bool) (*github.Tree, *github.Response, error)
+}
+
+type Option func(g *GitHub)
+
+func WithGitService(gitService GitService) Option {
+	return func(g *GitHub) {
+		g.gitService = gitService
+	}
+}
+
+// WithModulePath reads the module path declared in go.mod of each repository. Unless required, repositories whose
+// go.mod can't be read are returned without a module path.
+func WithModulePath(required bool) Option {
+	return func(g *GitHub) {
+		g.readModulePath = true
+		g.requireModulePath = required
+	}
 }
 
 type GitHub struct {
 	repositoriesService RepositoriesService
+	gitService          GitService
 	limiter             *rate.Limiter
 	owner               string
+	readModulePath      bool
+	requireModulePath   bool
+	httpClient          *http.Client
+	baseLimit           rate.Limit
+	baseBurst           int
+	rateLimit           rateLimitState
 }
 
-func New(repositoriesService RepositoriesService, limiter *rate.Limiter, owner string) *GitHub {
-	return &GitHub{
+func New(repositoriesService RepositoriesService, limiter *rate.Limiter, owner string, options ...Option) *GitHub {
+	g := &GitHub{
 		repositoriesService: repositoriesService,
 		limiter:             limiter,
 		owner:               owner,
+		baseLimit:           limiter.Limit(),
+		baseBurst:           limiter.Burst(),
 	}
+
+	for _, option := range options {
+		option(g)
+	}
+
+	return g
 }
 
 func (g *GitHub) Type() string {
@@ -39,22 +90,302 @@ func (g *GitHub) isValidRepo(repo string) bool {
 }
 
 func (g *GitHub) Fetch(ctx context.Context, repo string) (repository.Repository, error) {
+	return g.fetch(ctx, repo, nil)
+}
+
+func (g *GitHub) fetch(ctx context.Context, repo string, previous *Repository) (repository.Repository, error) {
 	if !g.isValidRepo(repo) {
-		return nil, errors.New("invalid repo")
+		return nil, repository.ErrInvalidName
+	}
+
+	if err := g.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	getCtx := ctx
+	if previous != nil {
+		getCtx = withValidators(ctx, previous.etag, previous.lastModified)
+	}
+
+	data, resp, err := g.repositoriesService.Get(getCtx, g.owner, repo)
+	g.observeRateLimit(resp, err)
+	if previous != nil && resp != nil && resp.Response != nil && resp.StatusCode == http.StatusNotModified {
+		return previous, nil
+	}
+	if err != nil {
+		return nil, classifyError(resp, err)
+	}
+
+	r := &Repository{repository: data}
+	if resp != nil && resp.Response != nil {
+		r.etag = resp.Header.Get("ETag")
+		r.lastModified = resp.Header.Get("Last-Modified")
+	}
+
+	if g.readModulePath {
+		if r.modulePath, err = g.declaredModulePath(ctx, repo); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+func (g *GitHub) FetchAll(ctx context.Context) (map[string]repository.Repository, error) {
+	data, err := g.listRepositories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	repositories := map[string]repository.Repository{}
+
+	for _, d := range data {
+		if !g.isValidRepo(d.GetName()) {
+			continue
+		}
+
+		r := &Repository{repository: d}
+
+		if g.readModulePath {
+			if r.modulePath, err = g.declaredModulePath(ctx, d.GetName()); err != nil {
+				return nil, err
+			}
+		}
+
+		repositories[d.GetName()] = r
 	}
 
-	if err := g.limiter.Wait(ctx); err != nil {
+	return repositories, nil
+}
+
+func (g *GitHub) listRepositories(ctx context.Context) ([]*github.Repository, error) {
+	repositories, err := g.listPages(ctx, func(listOptions github.ListOptions) ([]*github.Repository, *github.Response, error) {
+		return g.repositoriesService.ListByOrg(ctx, g.owner, &github.RepositoryListByOrgOptions{ListOptions: listOptions})
+	})
+	if !errors.Is(err, repository.ErrNotFound) {
+		return repositories, err
+	}
+
+	// The owner isn't an organization, so list the repositories of the user instead.
+	return g.listPages(ctx, func(listOptions github.ListOptions) ([]*github.Repository, *github.Response, error) {
+		return g.repositoriesService.List(ctx, g.owner, &github.RepositoryListOptions{ListOptions: listOptions})
+	})
+}
+
+func (g *GitHub) listPages(ctx context.Context, list func(listOptions github.ListOptions) ([]*github.Repository, *github.Response, error)) ([]*github.Repository, error) {
+	repositories := []*github.Repository{}
+	listOptions := github.ListOptions{PerPage: 100}
+
+	for {
+		if err := g.wait(ctx); err != nil {
+			return nil, err
+		}
+
+		page, resp, err := list(listOptions)
+		g.observeRateLimit(resp, err)
+		if err != nil {
+			return nil, classifyError(resp, err)
+		}
+
+		repositories = append(repositories, page...)
+
+		if resp == nil || resp.NextPage == 0 {
+			return repositories, nil
+		}
+
+		listOptions.Page = resp.NextPage
+	}
+}
+
+func (g *GitHub) getFile(ctx context.Context, repo, filePath string) (*github.RepositoryContent, error) {
+	if err := g.wait(ctx); err != nil {
 		return nil, err
 	}
 
-	data, resp, err := g.repositoriesService.Get(ctx, g.owner, repo)
+	file, _, resp, err := g.repositoriesService.GetContents(ctx, g.owner, repo, filePath, nil)
+	g.observeRateLimit(resp, err)
 	if err != nil {
 		if resp != nil && resp.StatusCode == http.StatusNotFound {
-			return nil, repository.ErrNotFound
+			return nil, nil
 		}
 
+		return nil, classifyError(resp, err)
+	}
+
+	return file, nil
+}
+
+func (g *GitHub) modulePath(ctx context.Context, repo string) (string, error) {
+	file, err := g.getFile(ctx, repo, "go.mod")
+	if err != nil || file == nil {
+		return "", err
+	}
+
+	content, err := file.GetContent()
+	if err != nil {
+		return "", err
+	}
+
+	return modfile.ModulePath([]byte(content)), nil
+}
+
+// declaredModulePath returns the module path of repo, ignoring errors unless the module path is required.
+func (g *GitHub) declaredModulePath(ctx context.Context, repo string) (string, error) {
+	modulePath, err := g.modulePath(ctx, repo)
+	if err != nil && !g.requireModulePath {
+		log.Printf("github: reading go.mod of %q: %s", repo, err)
+		return "", nil
+	}
+
+	return modulePath, err
+}
+
+func (g *GitHub) HasDirectory(ctx context.Context, repo, dir string) (bool, error) {
+	if !g.isValidRepo(repo) {
+		return false, repository.ErrInvalidName
+	}
+
+	if err := g.wait(ctx); err != nil {
+		return false, err
+	}
+
+	_, directoryContent, resp, err := g.repositoriesService.GetContents(ctx, g.owner, repo, dir, nil)
+	g.observeRateLimit(resp, err)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+
+		return false, classifyError(resp, err)
+	}
+
+	return directoryContent != nil, nil
+}
+
+func isModuleDir(dir string) bool {
+	for _, element := range strings.Split(dir, "/") {
+		if strings.HasPrefix(element, ".") || strings.HasPrefix(element, "_") || element == "testdata" || element == "vendor" {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (g *GitHub) Modules(ctx context.Context, repo string) ([]string, error) {
+	if !g.isValidRepo(repo) {
+		return nil, repository.ErrInvalidName
+	}
+
+	if g.gitService == nil {
+		return nil, errors.New("git service not configured")
+	}
+
+	files, err := g.goModFiles(ctx, repo, "HEAD", "")
+	if err != nil {
+		return nil, err
+	}
+
+	modules := []string{}
+	for _, file := range files {
+		dir := path.Dir(file)
+		if dir == "." || !isModuleDir(dir) || importpath.IsMajorVersion(path.Base(dir)) {
+			continue
+		}
+
+		modules = append(modules, dir)
+	}
+
+	sort.Strings(modules)
+
+	return modules, nil
+}
+
+// goModFiles returns the paths of all go.mod files in the tree sha, which is located at dir. Trees too large for a
+// single recursive listing are walked level by level.
+func (g *GitHub) goModFiles(ctx context.Context, repo, sha, dir string) ([]string, error) {
+	tree, err := g.getTree(ctx, repo, sha, true)
+	if err != nil {
 		return nil, err
 	}
 
-	return &Repository{repository: data}, nil
+	truncated := tree.GetTruncated()
+	if truncated {
+		if tree, err = g.getTree(ctx, repo, sha, false); err != nil {
+			return nil, err
+		}
+
+		if tree.GetTruncated() {
+			return nil, fmt.Errorf("tree of %q is too large to list", path.Join(repo, dir))
+		}
+	}
+
+	files := []string{}
+	for _, entry := range tree.Entries {
+		entryPath := path.Join(dir, entry.GetPath())
+
+		switch {
+		case entry.GetType() == "blob" && path.Base(entryPath) == "go.mod":
+			files = append(files, entryPath)
+		case entry.GetType() == "tree" && truncated && isModuleDir(entryPath):
+			subtreeFiles, err := g.goModFiles(ctx, repo, entry.GetSHA(), entryPath)
+			if err != nil {
+				return nil, err
+			}
+
+			files = append(files, subtreeFiles...)
+		}
+	}
+
+	return files, nil
+}
+
+func (g *GitHub) getTree(ctx context.Context, repo, sha string, recursive bool) (*github.Tree, error) {
+	if err := g.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	tree, resp, err := g.gitService.GetTree(ctx, g.owner, repo, sha, recursive)
+	g.observeRateLimit(resp, err)
+	if err != nil {
+		return nil, classifyError(resp, err)
+	}
+
+	return tree, nil
+}
+
+func (g *GitHub) MajorVersionLayout(ctx context.Context, repo, major string) (repository.MajorVersionLayout, error) {
+	if !g.isValidRepo(repo) {
+		return "", repository.ErrInvalidName
+	}
+
+	file, err := g.getFile(ctx, repo, path.Join(major, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+
+	if file != nil {
+		return repository.MajorVersionSubdirectory, nil
+	}
+
+	modulePath, err := g.modulePath(ctx, repo)
+	if err != nil {
+		return "", err
+	}
+
+	if strings.HasSuffix(modulePath, "/"+major) {
+		return repository.MajorVersionBranch, nil
+	}
+
+	if err := g.wait(ctx); err != nil {
+		return "", err
+	}
+
+	_, resp, err := g.repositoriesService.GetBranch(ctx, g.owner, repo, major, true)
+	g.observeRateLimit(resp, err)
+	if err != nil {
+		return "", classifyError(resp, err)
+	}
+
+	return repository.MajorVersionBranch, nil
 }
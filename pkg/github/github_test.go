@@ -3,24 +3,134 @@ package github
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/google/go-github/v52/github"
 	"go.eigsys.de/masquerade/pkg/repository"
 	"golang.org/x/time/rate"
 	"net/http"
+	"net/url"
 	"reflect"
 	"testing"
+	"time"
 )
 
 type mockRepositoriesService struct {
 	getRepository *github.Repository
 	getResponse   *github.Response
 	getError      error
+
+	getContentsFile      *github.RepositoryContent
+	getContentsFiles     map[string]*github.RepositoryContent
+	getContentsDirectory []*github.RepositoryContent
+	getContentsResponse  *github.Response
+	getContentsError     error
+
+	getBranchResponse *github.Response
+	getBranchError    error
+
+	listTagsPages    [][]*github.RepositoryTag
+	listTagsResponse *github.Response
+	listTagsError    error
+
+	getCommitCommit   *github.RepositoryCommit
+	getCommitResponse *github.Response
+	getCommitError    error
+
+	listByOrgPages    [][]*github.Repository
+	listByOrgResponse *github.Response
+	listByOrgError    error
+
+	listPages [][]*github.Repository
+	listError error
+
+	getArchiveLinkURL      *url.URL
+	getArchiveLinkResponse *github.Response
+	getArchiveLinkError    error
 }
 
 func (m *mockRepositoriesService) Get(_ context.Context, _, _ string) (*github.Repository, *github.Response, error) {
 	return m.getRepository, m.getResponse, m.getError
 }
 
+func (m *mockRepositoriesService) GetContents(_ context.Context, _, _, path string, _ *github.RepositoryContentGetOptions) (*github.RepositoryContent, []*github.RepositoryContent, *github.Response, error) {
+	if m.getContentsFiles != nil {
+		if file, ok := m.getContentsFiles[path]; ok {
+			return file, nil, nil, nil
+		}
+
+		return nil, nil, &github.Response{Response: &http.Response{StatusCode: http.StatusNotFound}}, errors.New("not found")
+	}
+
+	return m.getContentsFile, m.getContentsDirectory, m.getContentsResponse, m.getContentsError
+}
+
+func (m *mockRepositoriesService) GetBranch(_ context.Context, _, _, _ string, _ bool) (*github.Branch, *github.Response, error) {
+	return &github.Branch{}, m.getBranchResponse, m.getBranchError
+}
+
+func (m *mockRepositoriesService) ListTags(_ context.Context, _, _ string, opts *github.ListOptions) ([]*github.RepositoryTag, *github.Response, error) {
+	if m.listTagsError != nil {
+		return nil, m.listTagsResponse, m.listTagsError
+	}
+
+	page := max(opts.Page, 1)
+	response := &github.Response{}
+	if page < len(m.listTagsPages) {
+		response.NextPage = page + 1
+	}
+
+	return m.listTagsPages[page-1], response, nil
+}
+
+func (m *mockRepositoriesService) ListByOrg(_ context.Context, _ string, opts *github.RepositoryListByOrgOptions) ([]*github.Repository, *github.Response, error) {
+	if m.listByOrgError != nil {
+		return nil, m.listByOrgResponse, m.listByOrgError
+	}
+
+	return mockPage(m.listByOrgPages, opts.Page)
+}
+
+func (m *mockRepositoriesService) List(_ context.Context, _ string, opts *github.RepositoryListOptions) ([]*github.Repository, *github.Response, error) {
+	if m.listError != nil {
+		return nil, nil, m.listError
+	}
+
+	return mockPage(m.listPages, opts.Page)
+}
+
+func mockPage(pages [][]*github.Repository, page int) ([]*github.Repository, *github.Response, error) {
+	page = max(page, 1)
+	response := &github.Response{}
+	if page < len(pages) {
+		response.NextPage = page + 1
+	}
+
+	return pages[page-1], response, nil
+}
+
+func (m *mockRepositoriesService) GetCommit(_ context.Context, _, _, _ string, _ *github.ListOptions) (*github.RepositoryCommit, *github.Response, error) {
+	return m.getCommitCommit, m.getCommitResponse, m.getCommitError
+}
+
+func (m *mockRepositoriesService) GetArchiveLink(_ context.Context, _, _ string, _ github.ArchiveFormat, _ *github.RepositoryContentGetOptions, _ bool) (*url.URL, *github.Response, error) {
+	return m.getArchiveLinkURL, m.getArchiveLinkResponse, m.getArchiveLinkError
+}
+
+type mockGitService struct {
+	getTreeTree     *github.Tree
+	getTreeTrees    map[string]*github.Tree
+	getTreeResponse *github.Response
+	getTreeError    error
+}
+
+func (m *mockGitService) GetTree(_ context.Context, _, _, sha string, recursive bool) (*github.Tree, *github.Response, error) {
+	if m.getTreeTrees != nil {
+		return m.getTreeTrees[fmt.Sprintf("%s/%t", sha, recursive)], m.getTreeResponse, m.getTreeError
+	}
+
+	return m.getTreeTree, m.getTreeResponse, m.getTreeError
+}
+
 func TestGitHub_Type(t *testing.T) {
 	g := &GitHub{}
 
@@ -115,7 +225,8 @@ func TestGitHub_Fetch(t *testing.T) {
 			args: args{
 				repo: "the/repo",
 			},
-			wantErr: true,
+			wantErr:       true,
+			wantErrResult: repository.ErrInvalidName,
 		},
 		{
 			name: "limiter-error",
@@ -126,7 +237,75 @@ func TestGitHub_Fetch(t *testing.T) {
 				ctx:  context.Background(),
 				repo: "the-repo",
 			},
-			wantErr: true,
+			wantErr:       true,
+			wantErrResult: repository.ErrRateLimited,
+		},
+		{
+			name: "forbidden",
+			fields: fields{
+				repositoriesService: &mockRepositoriesService{
+					getResponse: &github.Response{
+						Response: &http.Response{StatusCode: http.StatusForbidden},
+					},
+					getError: errors.New("error"),
+				},
+				limiter: rate.NewLimiter(rate.Inf, 0),
+			},
+			args: args{
+				ctx:  context.Background(),
+				repo: "the-repo",
+			},
+			wantErr:       true,
+			wantErrResult: repository.ErrForbidden,
+		},
+		{
+			name: "rate-limited",
+			fields: fields{
+				repositoriesService: &mockRepositoriesService{
+					getResponse: &github.Response{
+						Response: &http.Response{StatusCode: http.StatusForbidden},
+					},
+					getError: &github.RateLimitError{Response: &http.Response{StatusCode: http.StatusForbidden, Request: &http.Request{URL: &url.URL{}}}},
+				},
+				limiter: rate.NewLimiter(rate.Inf, 0),
+			},
+			args: args{
+				ctx:  context.Background(),
+				repo: "the-repo",
+			},
+			wantErr:       true,
+			wantErrResult: repository.ErrRateLimited,
+		},
+		{
+			name: "unavailable",
+			fields: fields{
+				repositoriesService: &mockRepositoriesService{
+					getResponse: &github.Response{
+						Response: &http.Response{StatusCode: http.StatusBadGateway},
+					},
+					getError: errors.New("error"),
+				},
+				limiter: rate.NewLimiter(rate.Inf, 0),
+			},
+			args: args{
+				ctx:  context.Background(),
+				repo: "the-repo",
+			},
+			wantErr:       true,
+			wantErrResult: repository.ErrUnavailable,
+		},
+		{
+			name: "timeout",
+			fields: fields{
+				repositoriesService: &mockRepositoriesService{getError: context.DeadlineExceeded},
+				limiter:             rate.NewLimiter(rate.Inf, 0),
+			},
+			args: args{
+				ctx:  context.Background(),
+				repo: "the-repo",
+			},
+			wantErr:       true,
+			wantErrResult: repository.ErrTimeout,
 		},
 		{
 			name: "repository-not-found",
@@ -183,13 +362,583 @@ func TestGitHub_Fetch(t *testing.T) {
 	}
 }
 
+func TestGitHub_Fetch_modulePath(t *testing.T) {
+	genericError := errors.New("generic error")
+	tests := []struct {
+		name                string
+		repositoriesService RepositoriesService
+		required            bool
+		want                string
+		wantErr             bool
+		wantErrResult       error
+	}{
+		{
+			name: "ok",
+			repositoriesService: &mockRepositoriesService{
+				getRepository:   &github.Repository{},
+				getContentsFile: &github.RepositoryContent{Content: github.String("bW9kdWxlIGdvLmV4YW1wbGUuY29tL3JlcG8K"), Encoding: github.String("base64")},
+			},
+			want: "go.example.com/repo",
+		},
+		{
+			name: "go-mod-not-found",
+			repositoriesService: &mockRepositoriesService{
+				getRepository: &github.Repository{},
+				getContentsResponse: &github.Response{
+					Response: &http.Response{StatusCode: http.StatusNotFound},
+				},
+				getContentsError: errors.New("error"),
+			},
+			want: "",
+		},
+		{
+			name: "go-mod-is-directory",
+			repositoriesService: &mockRepositoriesService{
+				getRepository:        &github.Repository{},
+				getContentsDirectory: []*github.RepositoryContent{},
+			},
+			want: "",
+		},
+		{
+			name: "invalid-encoding",
+			repositoriesService: &mockRepositoriesService{
+				getRepository:   &github.Repository{},
+				getContentsFile: &github.RepositoryContent{Content: github.String("module"), Encoding: github.String("invalid")},
+			},
+			required: true,
+			wantErr:  true,
+		},
+		{
+			name: "generic-error",
+			repositoriesService: &mockRepositoriesService{
+				getRepository:    &github.Repository{},
+				getContentsError: genericError,
+			},
+			required:      true,
+			wantErr:       true,
+			wantErrResult: genericError,
+		},
+		{
+			name: "server-error",
+			repositoriesService: &mockRepositoriesService{
+				getRepository: &github.Repository{},
+				getContentsResponse: &github.Response{
+					Response: &http.Response{StatusCode: http.StatusInternalServerError},
+				},
+				getContentsError: errors.New("error"),
+			},
+			required:      true,
+			wantErr:       true,
+			wantErrResult: repository.ErrUnavailable,
+		},
+		{
+			name: "server-error-not-required",
+			repositoriesService: &mockRepositoriesService{
+				getRepository: &github.Repository{},
+				getContentsResponse: &github.Response{
+					Response: &http.Response{StatusCode: http.StatusInternalServerError},
+				},
+				getContentsError: errors.New("error"),
+			},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New(tt.repositoriesService, rate.NewLimiter(rate.Inf, 0), "the-owner", WithModulePath(tt.required))
+			got, err := g.Fetch(context.Background(), "the-repo")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Fetch() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErrResult != nil && !errors.Is(err, tt.wantErrResult) {
+				t.Errorf("Fetch() error = %v, wantErrResult %v", err, tt.wantErrResult)
+				return
+			}
+			if err == nil && got.(*Repository).GetModulePath() != tt.want {
+				t.Errorf("GetModulePath() = %v, want %v", got.(*Repository).GetModulePath(), tt.want)
+			}
+		})
+	}
+}
+
+func TestGitHub_Fetch_modulePathLimiterError(t *testing.T) {
+	limiter := rate.NewLimiter(rate.Every(time.Hour), 1)
+	g := New(&mockRepositoriesService{getRepository: &github.Repository{}}, limiter, "the-owner", WithModulePath(true))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if _, err := g.Fetch(ctx, "the-repo"); err == nil {
+		t.Error("no error")
+	}
+}
+
+func TestGitHub_HasDirectory(t *testing.T) {
+	genericError := errors.New("generic error")
+	type fields struct {
+		repositoriesService RepositoriesService
+		limiter             *rate.Limiter
+	}
+	type args struct {
+		repo string
+		dir  string
+	}
+	tests := []struct {
+		name          string
+		fields        fields
+		args          args
+		want          bool
+		wantErr       bool
+		wantErrResult error
+	}{
+		{
+			name: "directory",
+			fields: fields{
+				repositoriesService: &mockRepositoriesService{getContentsDirectory: []*github.RepositoryContent{}},
+				limiter:             rate.NewLimiter(rate.Inf, 0),
+			},
+			args: args{repo: "the-repo", dir: "sub/pkg"},
+			want: true,
+		},
+		{
+			name: "file",
+			fields: fields{
+				repositoriesService: &mockRepositoriesService{getContentsFile: &github.RepositoryContent{}},
+				limiter:             rate.NewLimiter(rate.Inf, 0),
+			},
+			args: args{repo: "the-repo", dir: "sub/pkg"},
+			want: false,
+		},
+		{
+			name: "not-found",
+			fields: fields{
+				repositoriesService: &mockRepositoriesService{
+					getContentsResponse: &github.Response{
+						Response: &http.Response{StatusCode: http.StatusNotFound},
+					},
+					getContentsError: errors.New("error"),
+				},
+				limiter: rate.NewLimiter(rate.Inf, 0),
+			},
+			args: args{repo: "the-repo", dir: "sub/pkg"},
+			want: false,
+		},
+		{
+			name:    "invalid-repo",
+			args:    args{repo: "the/repo", dir: "sub/pkg"},
+			wantErr: true,
+		},
+		{
+			name: "limiter-error",
+			fields: fields{
+				limiter: rate.NewLimiter(0, 0),
+			},
+			args:    args{repo: "the-repo", dir: "sub/pkg"},
+			wantErr: true,
+		},
+		{
+			name: "generic-error",
+			fields: fields{
+				repositoriesService: &mockRepositoriesService{getContentsError: genericError},
+				limiter:             rate.NewLimiter(rate.Inf, 0),
+			},
+			args:          args{repo: "the-repo", dir: "sub/pkg"},
+			wantErr:       true,
+			wantErrResult: genericError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &GitHub{
+				repositoriesService: tt.fields.repositoriesService,
+				limiter:             tt.fields.limiter,
+			}
+			got, err := g.HasDirectory(context.Background(), tt.args.repo, tt.args.dir)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("HasDirectory() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErrResult != nil && !errors.Is(err, tt.wantErrResult) {
+				t.Errorf("HasDirectory() error = %v, wantErrResult %v", err, tt.wantErrResult)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("HasDirectory() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitHub_Modules(t *testing.T) {
+	genericError := errors.New("generic error")
+	blob := func(path string) *github.TreeEntry {
+		return &github.TreeEntry{Path: github.String(path), Type: github.String("blob")}
+	}
+	subtree := func(path, sha string) *github.TreeEntry {
+		return &github.TreeEntry{Path: github.String(path), SHA: github.String(sha), Type: github.String("tree")}
+	}
+	truncated := func(entries ...*github.TreeEntry) *github.Tree {
+		return &github.Tree{Entries: entries, Truncated: github.Bool(true)}
+	}
+	type fields struct {
+		gitService GitService
+		limiter    *rate.Limiter
+	}
+	tests := []struct {
+		name          string
+		fields        fields
+		repo          string
+		want          []string
+		wantErr       bool
+		wantErrResult error
+	}{
+		{
+			name: "ok",
+			fields: fields{
+				gitService: &mockGitService{getTreeTree: &github.Tree{Entries: []*github.TreeEntry{
+					blob("go.mod"),
+					blob("tools/go.mod"),
+					blob("api/go.mod"),
+					blob("api/v2/go.mod"),
+					blob("api/v2/api.go"),
+					blob("v2/go.mod"),
+					{Path: github.String("dir/go.mod"), Type: github.String("tree")},
+					blob("internal/testdata/go.mod"),
+					blob("vendor/example.com/go.mod"),
+					blob(".github/go.mod"),
+					blob("_examples/go.mod"),
+				}}},
+				limiter: rate.NewLimiter(rate.Inf, 0),
+			},
+			repo: "the-repo",
+			want: []string{"api", "tools"},
+		},
+		{
+			name: "truncated",
+			fields: fields{
+				gitService: &mockGitService{getTreeTrees: map[string]*github.Tree{
+					"HEAD/true":  truncated(blob("go.mod"), subtree("api", "api-sha")),
+					"HEAD/false": {Entries: []*github.TreeEntry{blob("go.mod"), subtree("api", "api-sha"), subtree("tools", "tools-sha"), subtree("testdata", "testdata-sha")}},
+					"api-sha/true": {Entries: []*github.TreeEntry{
+						blob("go.mod"),
+						blob("v2/go.mod"),
+					}},
+					"tools-sha/true":  truncated(subtree("cmd", "cmd-sha")),
+					"tools-sha/false": {Entries: []*github.TreeEntry{blob("go.mod"), subtree("cmd", "cmd-sha")}},
+					"cmd-sha/true":    {Entries: []*github.TreeEntry{blob("lint/go.mod")}},
+				}},
+				limiter: rate.NewLimiter(rate.Inf, 0),
+			},
+			repo: "the-repo",
+			want: []string{"api", "tools", "tools/cmd/lint"},
+		},
+		{
+			name: "truncated-directory",
+			fields: fields{
+				gitService: &mockGitService{getTreeTrees: map[string]*github.Tree{
+					"HEAD/true":  truncated(),
+					"HEAD/false": truncated(),
+				}},
+				limiter: rate.NewLimiter(rate.Inf, 0),
+			},
+			repo:    "the-repo",
+			wantErr: true,
+		},
+		{
+			name: "no-modules",
+			fields: fields{
+				gitService: &mockGitService{getTreeTree: &github.Tree{}},
+				limiter:    rate.NewLimiter(rate.Inf, 0),
+			},
+			repo: "the-repo",
+			want: []string{},
+		},
+		{
+			name:    "invalid-repo",
+			repo:    "the/repo",
+			wantErr: true,
+		},
+		{
+			name:    "git-service-missing",
+			repo:    "the-repo",
+			wantErr: true,
+		},
+		{
+			name: "limiter-error",
+			fields: fields{
+				gitService: &mockGitService{},
+				limiter:    rate.NewLimiter(0, 0),
+			},
+			repo:    "the-repo",
+			wantErr: true,
+		},
+		{
+			name: "not-found",
+			fields: fields{
+				gitService: &mockGitService{
+					getTreeResponse: &github.Response{
+						Response: &http.Response{StatusCode: http.StatusNotFound},
+					},
+					getTreeError: errors.New("error"),
+				},
+				limiter: rate.NewLimiter(rate.Inf, 0),
+			},
+			repo:          "the-repo",
+			wantErr:       true,
+			wantErrResult: repository.ErrNotFound,
+		},
+		{
+			name: "generic-error",
+			fields: fields{
+				gitService: &mockGitService{getTreeError: genericError},
+				limiter:    rate.NewLimiter(rate.Inf, 0),
+			},
+			repo:          "the-repo",
+			wantErr:       true,
+			wantErrResult: genericError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &GitHub{
+				gitService: tt.fields.gitService,
+				limiter:    tt.fields.limiter,
+			}
+			got, err := g.Modules(context.Background(), tt.repo)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Modules() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErrResult != nil && !errors.Is(err, tt.wantErrResult) {
+				t.Errorf("Modules() error = %v, wantErrResult %v", err, tt.wantErrResult)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Modules() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitHub_MajorVersionLayout(t *testing.T) {
+	genericError := errors.New("generic error")
+	notFound := &github.Response{Response: &http.Response{StatusCode: http.StatusNotFound}}
+	goMod := func(modulePath string) *github.RepositoryContent {
+		return &github.RepositoryContent{Content: github.String("module " + modulePath + "\n")}
+	}
+	tests := []struct {
+		name                string
+		repositoriesService RepositoriesService
+		repo                string
+		want                repository.MajorVersionLayout
+		wantErr             bool
+		wantErrResult       error
+	}{
+		{
+			name: "subdirectory",
+			repositoriesService: &mockRepositoriesService{getContentsFiles: map[string]*github.RepositoryContent{
+				"go.mod":    goMod("go.example.com/the-repo"),
+				"v2/go.mod": goMod("go.example.com/the-repo/v2"),
+			}},
+			repo: "the-repo",
+			want: repository.MajorVersionSubdirectory,
+		},
+		{
+			name: "default-branch",
+			repositoriesService: &mockRepositoriesService{getContentsFiles: map[string]*github.RepositoryContent{
+				"go.mod": goMod("go.example.com/the-repo/v2"),
+			}},
+			repo: "the-repo",
+			want: repository.MajorVersionBranch,
+		},
+		{
+			name: "major-branch",
+			repositoriesService: &mockRepositoriesService{getContentsFiles: map[string]*github.RepositoryContent{
+				"go.mod": goMod("go.example.com/the-repo"),
+			}},
+			repo: "the-repo",
+			want: repository.MajorVersionBranch,
+		},
+		{
+			name: "not-found",
+			repositoriesService: &mockRepositoriesService{
+				getContentsFiles: map[string]*github.RepositoryContent{
+					"go.mod": goMod("go.example.com/the-repo"),
+				},
+				getBranchResponse: notFound,
+				getBranchError:    errors.New("error"),
+			},
+			repo:          "the-repo",
+			wantErr:       true,
+			wantErrResult: repository.ErrNotFound,
+		},
+		{
+			name: "branch-error",
+			repositoriesService: &mockRepositoriesService{
+				getContentsFiles: map[string]*github.RepositoryContent{},
+				getBranchError:   genericError,
+			},
+			repo:          "the-repo",
+			wantErr:       true,
+			wantErrResult: genericError,
+		},
+		{
+			name:                "contents-error",
+			repositoriesService: &mockRepositoriesService{getContentsError: genericError},
+			repo:                "the-repo",
+			wantErr:             true,
+			wantErrResult:       genericError,
+		},
+		{
+			name: "invalid-go-mod",
+			repositoriesService: &mockRepositoriesService{getContentsFiles: map[string]*github.RepositoryContent{
+				"go.mod": {Content: github.String("module"), Encoding: github.String("invalid")},
+			}},
+			repo:    "the-repo",
+			wantErr: true,
+		},
+		{
+			name:    "invalid-repo",
+			repo:    "the/repo",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New(tt.repositoriesService, rate.NewLimiter(rate.Inf, 0), "the-owner")
+			got, err := g.MajorVersionLayout(context.Background(), tt.repo, "v2")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("MajorVersionLayout() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErrResult != nil && !errors.Is(err, tt.wantErrResult) {
+				t.Errorf("MajorVersionLayout() error = %v, wantErrResult %v", err, tt.wantErrResult)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("MajorVersionLayout() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitHub_MajorVersionLayout_limiterError(t *testing.T) {
+	repositoriesService := &mockRepositoriesService{getContentsFiles: map[string]*github.RepositoryContent{}}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	for burst := 0; burst < 3; burst++ {
+		g := New(repositoriesService, rate.NewLimiter(rate.Every(time.Hour), burst), "the-owner")
+		if _, err := g.MajorVersionLayout(ctx, "the-repo", "v2"); err == nil {
+			t.Errorf("no error with burst %d", burst)
+		}
+	}
+}
+
+func TestGitHub_FetchAll(t *testing.T) {
+	notFound := &github.Response{Response: &http.Response{StatusCode: http.StatusNotFound}}
+	foo := &github.Repository{Name: github.String("foo")}
+	bar := &github.Repository{Name: github.String("bar")}
+	invalid := &github.Repository{Name: github.String("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")}
+	tests := []struct {
+		name                string
+		repositoriesService *mockRepositoriesService
+		options             []Option
+		want                map[string]repository.Repository
+		wantErr             bool
+	}{
+		{
+			name:                "organization",
+			repositoriesService: &mockRepositoriesService{listByOrgPages: [][]*github.Repository{{foo}, {bar, invalid}}},
+			want:                map[string]repository.Repository{"foo": &Repository{repository: foo}, "bar": &Repository{repository: bar}},
+		},
+		{
+			name: "user",
+			repositoriesService: &mockRepositoriesService{
+				listByOrgResponse: notFound,
+				listByOrgError:    errors.New("not found"),
+				listPages:         [][]*github.Repository{{foo}},
+			},
+			want: map[string]repository.Repository{"foo": &Repository{repository: foo}},
+		},
+		{
+			name: "module-path",
+			repositoriesService: &mockRepositoriesService{
+				listByOrgPages: [][]*github.Repository{{foo}},
+				getContentsFile: &github.RepositoryContent{
+					Content: github.String("module go.example.com/foo\n"),
+				},
+			},
+			options: []Option{WithModulePath(false)},
+			want:    map[string]repository.Repository{"foo": &Repository{repository: foo, modulePath: "go.example.com/foo"}},
+		},
+		{
+			name: "module-path-error",
+			repositoriesService: &mockRepositoriesService{
+				listByOrgPages:   [][]*github.Repository{{foo}},
+				getContentsError: errors.New("error"),
+			},
+			options: []Option{WithModulePath(true)},
+			wantErr: true,
+		},
+		{
+			name: "module-path-error-not-required",
+			repositoriesService: &mockRepositoriesService{
+				listByOrgPages:   [][]*github.Repository{{foo}},
+				getContentsError: errors.New("error"),
+			},
+			options: []Option{WithModulePath(false)},
+			want:    map[string]repository.Repository{"foo": &Repository{repository: foo}},
+		},
+		{
+			name:                "organization-error",
+			repositoriesService: &mockRepositoriesService{listByOrgError: errors.New("error")},
+			wantErr:             true,
+		},
+		{
+			name: "user-error",
+			repositoriesService: &mockRepositoriesService{
+				listByOrgResponse: notFound,
+				listByOrgError:    errors.New("not found"),
+				listError:         errors.New("error"),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New(tt.repositoriesService, rate.NewLimiter(rate.Inf, 0), "the-owner", tt.options...)
+			got, err := g.FetchAll(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("FetchAll() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FetchAll() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitHub_FetchAll_limiterError(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	g := New(&mockRepositoriesService{}, rate.NewLimiter(rate.Every(time.Hour), 0), "the-owner")
+	if _, err := g.FetchAll(ctx); err == nil {
+		t.Error("FetchAll() no error")
+	}
+}
+
 func TestNew(t *testing.T) {
 	repositoriesService := &mockRepositoriesService{}
+	gitService := &mockGitService{}
 	limiter := rate.NewLimiter(0, 0)
 	owner := "the-owner"
-	g := New(repositoriesService, limiter, owner)
+	g := New(repositoriesService, limiter, owner, WithGitService(gitService))
 	want := &GitHub{
 		repositoriesService: repositoriesService,
+		gitService:          gitService,
 		limiter:             limiter,
 		owner:               owner,
 	}
@@ -0,0 +1,114 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/go-github/v52/github"
+	"go.eigsys.de/masquerade/pkg/repository"
+	"golang.org/x/time/rate"
+	"sync"
+	"time"
+)
+
+// secondaryRateLimitBackoff is used if GitHub doesn't announce when to retry after hitting a secondary rate limit.
+const secondaryRateLimitBackoff = time.Minute
+
+// rateLimitThreshold is the fraction of the quota below which the remaining requests are spread until the reset.
+const rateLimitThreshold = 0.1
+
+type rateLimitState struct {
+	mu           sync.Mutex
+	rate         github.Rate
+	backoffUntil time.Time
+}
+
+func (g *GitHub) RateLimitRemaining() (int, bool) {
+	g.rateLimit.mu.Lock()
+	defer g.rateLimit.mu.Unlock()
+
+	return g.rateLimit.rate.Remaining, g.rateLimit.rate.Limit > 0
+}
+
+func (g *GitHub) wait(ctx context.Context) error {
+	if retryAfter := g.backoffRemaining(); retryAfter > 0 {
+		return &repository.RetryAfterError{
+			Err:        fmt.Errorf("%w: backing off for %s", repository.ErrRateLimited, retryAfter.Round(time.Second)),
+			RetryAfter: retryAfter,
+		}
+	}
+
+	err := g.limiter.Wait(ctx)
+
+	switch {
+	case err == nil || errors.Is(err, context.Canceled):
+		return err
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("%w: %w", repository.ErrTimeout, err)
+	default:
+		// The limiter refuses to wait beyond the deadline of the context.
+		return fmt.Errorf("%w: %w", repository.ErrRateLimited, err)
+	}
+}
+
+func (g *GitHub) observeRateLimit(resp *github.Response, err error) {
+	var rateLimitErr *github.RateLimitError
+	var abuseRateLimitErr *github.AbuseRateLimitError
+
+	switch {
+	case errors.As(err, &rateLimitErr):
+		g.backOff(rateLimitErr.Rate.Reset.Time)
+	case errors.As(err, &abuseRateLimitErr):
+		retryAfter := secondaryRateLimitBackoff
+		if abuseRateLimitErr.RetryAfter != nil {
+			retryAfter = *abuseRateLimitErr.RetryAfter
+		}
+
+		g.backOff(time.Now().Add(retryAfter))
+	}
+
+	if resp == nil || resp.Rate.Limit == 0 {
+		return
+	}
+
+	g.rateLimit.mu.Lock()
+	g.rateLimit.rate = resp.Rate
+	g.rateLimit.mu.Unlock()
+
+	g.adaptLimiter(resp.Rate)
+}
+
+func (g *GitHub) adaptLimiter(r github.Rate) {
+	untilReset := time.Until(r.Reset.Time)
+
+	if r.Remaining <= 0 && untilReset > 0 {
+		g.backOff(r.Reset.Time)
+		return
+	}
+
+	limit, burst := g.baseLimit, g.baseBurst
+
+	if untilReset > 0 && float64(r.Remaining) < float64(r.Limit)*rateLimitThreshold {
+		limit = min(limit, rate.Limit(float64(r.Remaining)/untilReset.Seconds()))
+		burst = max(min(burst, r.Remaining), 1)
+	}
+
+	g.limiter.SetLimit(limit)
+	g.limiter.SetBurst(burst)
+}
+
+func (g *GitHub) backOff(until time.Time) {
+	g.rateLimit.mu.Lock()
+	defer g.rateLimit.mu.Unlock()
+
+	if until.After(g.rateLimit.backoffUntil) {
+		g.rateLimit.backoffUntil = until
+	}
+}
+
+func (g *GitHub) backoffRemaining() time.Duration {
+	g.rateLimit.mu.Lock()
+	defer g.rateLimit.mu.Unlock()
+
+	return time.Until(g.rateLimit.backoffUntil)
+}
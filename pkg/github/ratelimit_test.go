@@ -0,0 +1,127 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"github.com/google/go-github/v52/github"
+	"go.eigsys.de/masquerade/pkg/repository"
+	"golang.org/x/time/rate"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestGitHub_adaptLimiter(t *testing.T) {
+	reset := github.Timestamp{Time: time.Now().Add(100 * time.Second)}
+	tests := []struct {
+		name        string
+		rate        github.Rate
+		wantLimit   rate.Limit
+		wantBurst   int
+		wantBackoff bool
+	}{
+		{
+			name:      "plenty",
+			rate:      github.Rate{Limit: 5000, Remaining: 4000, Reset: reset},
+			wantLimit: 25,
+			wantBurst: 100,
+		},
+		{
+			name:      "low",
+			rate:      github.Rate{Limit: 5000, Remaining: 50, Reset: reset},
+			wantLimit: 0.5,
+			wantBurst: 50,
+		},
+		{
+			name:        "exhausted",
+			rate:        github.Rate{Limit: 5000, Remaining: 0, Reset: reset},
+			wantLimit:   25,
+			wantBurst:   100,
+			wantBackoff: true,
+		},
+		{
+			name:      "reset",
+			rate:      github.Rate{Limit: 5000, Remaining: 0, Reset: github.Timestamp{Time: time.Now().Add(-time.Second)}},
+			wantLimit: 25,
+			wantBurst: 100,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New(&mockRepositoriesService{}, rate.NewLimiter(25, 100), "the-owner")
+
+			g.adaptLimiter(tt.rate)
+
+			if limit := g.limiter.Limit(); limit < tt.wantLimit*0.9 || limit > tt.wantLimit*1.1 {
+				t.Errorf("adaptLimiter() limit = %v, want %v", limit, tt.wantLimit)
+			}
+			if burst := g.limiter.Burst(); burst != tt.wantBurst {
+				t.Errorf("adaptLimiter() burst = %v, want %v", burst, tt.wantBurst)
+			}
+			if backoff := g.backoffRemaining() > 0; backoff != tt.wantBackoff {
+				t.Errorf("adaptLimiter() backoff = %v, want %v", backoff, tt.wantBackoff)
+			}
+		})
+	}
+}
+
+func TestGitHub_adaptLimiter_restore(t *testing.T) {
+	g := New(&mockRepositoriesService{}, rate.NewLimiter(25, 100), "the-owner")
+	reset := github.Timestamp{Time: time.Now().Add(time.Hour)}
+
+	g.adaptLimiter(github.Rate{Limit: 5000, Remaining: 10, Reset: reset})
+	g.adaptLimiter(github.Rate{Limit: 5000, Remaining: 5000, Reset: reset})
+
+	if g.limiter.Limit() != 25 || g.limiter.Burst() != 100 {
+		t.Errorf("limiter not restored: limit = %v, burst = %v", g.limiter.Limit(), g.limiter.Burst())
+	}
+}
+
+func TestGitHub_observeRateLimit_secondary(t *testing.T) {
+	repositoriesService := &mockRepositoriesService{
+		getResponse: &github.Response{Response: &http.Response{StatusCode: http.StatusForbidden}},
+		getError: &github.AbuseRateLimitError{
+			Response: &http.Response{StatusCode: http.StatusForbidden, Request: &http.Request{URL: &url.URL{}}},
+		},
+	}
+	g := New(repositoriesService, rate.NewLimiter(rate.Inf, 0), "the-owner")
+
+	if _, err := g.Fetch(context.Background(), "the-repo"); !errors.Is(err, repository.ErrRateLimited) {
+		t.Fatalf("Fetch() error = %v, want %v", err, repository.ErrRateLimited)
+	}
+
+	repositoriesService.getResponse, repositoriesService.getError = nil, nil
+
+	_, err := g.Fetch(context.Background(), "the-repo")
+
+	var retryAfterErr *repository.RetryAfterError
+	if !errors.As(err, &retryAfterErr) || !errors.Is(err, repository.ErrRateLimited) {
+		t.Fatalf("Fetch() error = %v, want backoff", err)
+	}
+	if retryAfterErr.RetryAfter <= 0 || retryAfterErr.RetryAfter > secondaryRateLimitBackoff {
+		t.Errorf("Fetch() retry after = %v", retryAfterErr.RetryAfter)
+	}
+}
+
+func TestGitHub_RateLimitRemaining(t *testing.T) {
+	repositoriesService := &mockRepositoriesService{getRepository: &github.Repository{}}
+	g := New(repositoriesService, rate.NewLimiter(rate.Inf, 0), "the-owner")
+
+	if _, ok := g.RateLimitRemaining(); ok {
+		t.Error("RateLimitRemaining() known before the first response")
+	}
+
+	repositoriesService.getResponse = &github.Response{
+		Response: &http.Response{StatusCode: http.StatusOK},
+		Rate:     github.Rate{Limit: 5000, Remaining: 4321, Reset: github.Timestamp{Time: time.Now().Add(time.Hour)}},
+	}
+
+	if _, err := g.Fetch(context.Background(), "the-repo"); err != nil {
+		t.Fatal(err)
+	}
+
+	if remaining, ok := g.RateLimitRemaining(); !ok || remaining != 4321 {
+		t.Errorf("RateLimitRemaining() = %v, %v, want 4321, true", remaining, ok)
+	}
+}
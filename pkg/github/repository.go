@@ -1,9 +1,51 @@
 package github
 
-import "github.com/google/go-github/v52/github"
+import (
+	"encoding/json"
+	"github.com/google/go-github/v52/github"
+	"go.eigsys.de/masquerade/pkg/repository"
+)
+
+const (
+	sourceDirectoryPath = "tree"
+	sourceFilePath      = "blob"
+)
 
 type Repository struct {
-	repository *github.Repository
+	repository   *github.Repository
+	modulePath   string
+	etag         string
+	lastModified string
+}
+
+type repositoryData struct {
+	Repository   *github.Repository `json:"repository"`
+	ModulePath   string             `json:"module_path,omitempty"`
+	ETag         string             `json:"etag,omitempty"`
+	LastModified string             `json:"last_modified,omitempty"`
+}
+
+func (r *Repository) MarshalBinary() ([]byte, error) {
+	return json.Marshal(&repositoryData{
+		Repository:   r.repository,
+		ModulePath:   r.modulePath,
+		ETag:         r.etag,
+		LastModified: r.lastModified,
+	})
+}
+
+func (r *Repository) UnmarshalBinary(data []byte) error {
+	repositoryData := &repositoryData{}
+	if err := json.Unmarshal(data, repositoryData); err != nil {
+		return err
+	}
+
+	r.repository = repositoryData.Repository
+	r.modulePath = repositoryData.ModulePath
+	r.etag = repositoryData.ETag
+	r.lastModified = repositoryData.LastModified
+
+	return nil
 }
 
 func (r *Repository) GetRepoRoot() string {
@@ -25,3 +67,15 @@ func (r *Repository) GetProjectWebsiteOrFallback(fallback string) string {
 
 	return projectWebsite
 }
+
+func (r *Repository) GetModulePath() string {
+	return r.modulePath
+}
+
+func (r *Repository) GetSourceURLs(dir string) repository.SourceURLs {
+	if r.repository == nil {
+		return repository.SourceURLs{}
+	}
+
+	return repository.NewSourceURLs(r.repository.GetHTMLURL(), r.repository.GetDefaultBranch(), dir, sourceDirectoryPath, sourceFilePath)
+}
@@ -2,6 +2,8 @@ package github
 
 import (
 	"github.com/google/go-github/v52/github"
+	"go.eigsys.de/masquerade/pkg/repository"
+	"reflect"
 	"testing"
 )
 
@@ -78,3 +80,75 @@ func TestRepository_GetProjectWebsiteOrFallback(t *testing.T) {
 		})
 	}
 }
+
+func TestRepository_GetModulePath(t *testing.T) {
+	r := &Repository{modulePath: "go.example.com/repo"}
+
+	if got := r.GetModulePath(); got != "go.example.com/repo" {
+		t.Errorf("GetModulePath() = %v", got)
+	}
+}
+
+func TestRepository_GetSourceURLs(t *testing.T) {
+	r := &Repository{repository: &github.Repository{HTMLURL: github.String("https://github.com/owner/repo"), DefaultBranch: github.String("main")}}
+	want := repository.SourceURLs{
+		Home:      "https://github.com/owner/repo",
+		Directory: "https://github.com/owner/repo/tree/main/tools{/dir}",
+		File:      "https://github.com/owner/repo/blob/main/tools{/dir}/{file}#L{line}",
+	}
+
+	if got := r.GetSourceURLs("tools"); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetSourceURLs() = %v, want %v", got, want)
+	}
+
+	if got := (&Repository{}).GetSourceURLs("tools"); !reflect.DeepEqual(got, repository.SourceURLs{}) {
+		t.Errorf("GetSourceURLs() = %v, want empty", got)
+	}
+}
+
+func TestRepository_MarshalBinary(t *testing.T) {
+	tests := []struct {
+		name string
+		r    *Repository
+	}{
+		{
+			name: "everything-given",
+			r: &Repository{
+				repository: &github.Repository{
+					HTMLURL:       github.String("https://github.com/owner/repo"),
+					Homepage:      github.String("https://example.com"),
+					DefaultBranch: github.String("main"),
+				},
+				modulePath:   "go.example.com/repo",
+				etag:         `"abc"`,
+				lastModified: "Mon, 01 Jan 2024 00:00:00 GMT",
+			},
+		},
+		{
+			name: "repository-nil",
+			r:    &Repository{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.r.MarshalBinary()
+			if err != nil {
+				t.Fatalf("MarshalBinary() error = %v", err)
+			}
+
+			got := &Repository{}
+			if err := got.UnmarshalBinary(data); err != nil {
+				t.Fatalf("UnmarshalBinary() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.r) {
+				t.Errorf("UnmarshalBinary() got = %+v, want %+v", got, tt.r)
+			}
+		})
+	}
+}
+
+func TestRepository_UnmarshalBinary_invalid(t *testing.T) {
+	if err := (&Repository{}).UnmarshalBinary([]byte("{")); err == nil {
+		t.Error("UnmarshalBinary() no error")
+	}
+}
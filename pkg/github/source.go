@@ -0,0 +1,164 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/go-github/v52/github"
+	"go.eigsys.de/masquerade/pkg/repository"
+	modzip "golang.org/x/mod/zip"
+	"io"
+	"net/http"
+	"time"
+)
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(g *GitHub) {
+		g.httpClient = httpClient
+	}
+}
+
+func (g *GitHub) Private(ctx context.Context, repo string) (bool, error) {
+	if !g.isValidRepo(repo) {
+		return false, repository.ErrInvalidName
+	}
+
+	if err := g.wait(ctx); err != nil {
+		return false, err
+	}
+
+	data, resp, err := g.repositoriesService.Get(ctx, g.owner, repo)
+	g.observeRateLimit(resp, err)
+	if err != nil {
+		return false, classifyError(resp, err)
+	}
+
+	return data.GetPrivate(), nil
+}
+
+func (g *GitHub) Tags(ctx context.Context, repo string) ([]string, error) {
+	if !g.isValidRepo(repo) {
+		return nil, repository.ErrInvalidName
+	}
+
+	tags := []string{}
+	opts := &github.ListOptions{PerPage: 100}
+
+	for {
+		if err := g.wait(ctx); err != nil {
+			return nil, err
+		}
+
+		page, resp, err := g.repositoriesService.ListTags(ctx, g.owner, repo, opts)
+		g.observeRateLimit(resp, err)
+		if err != nil {
+			return nil, classifyError(resp, err)
+		}
+
+		for _, tag := range page {
+			tags = append(tags, tag.GetName())
+		}
+
+		if resp == nil || resp.NextPage == 0 {
+			return tags, nil
+		}
+
+		opts.Page = resp.NextPage
+	}
+}
+
+func (g *GitHub) CommitTime(ctx context.Context, repo, ref string) (time.Time, error) {
+	if !g.isValidRepo(repo) {
+		return time.Time{}, repository.ErrInvalidName
+	}
+
+	if err := g.wait(ctx); err != nil {
+		return time.Time{}, err
+	}
+
+	commit, resp, err := g.repositoriesService.GetCommit(ctx, g.owner, repo, ref, nil)
+	g.observeRateLimit(resp, err)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusUnprocessableEntity {
+			return time.Time{}, repository.ErrNotFound
+		}
+
+		return time.Time{}, classifyError(resp, err)
+	}
+
+	return commit.GetCommit().GetCommitter().GetDate().UTC(), nil
+}
+
+func (g *GitHub) File(ctx context.Context, repo, ref, filePath string) ([]byte, error) {
+	if !g.isValidRepo(repo) {
+		return nil, repository.ErrInvalidName
+	}
+
+	if err := g.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	file, _, resp, err := g.repositoriesService.GetContents(ctx, g.owner, repo, filePath, &github.RepositoryContentGetOptions{Ref: ref})
+	g.observeRateLimit(resp, err)
+	if err != nil {
+		return nil, classifyError(resp, err)
+	}
+
+	if file == nil {
+		return nil, repository.ErrNotFound
+	}
+
+	content, err := file.GetContent()
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(content), nil
+}
+
+func (g *GitHub) Archive(ctx context.Context, repo, ref string) ([]byte, error) {
+	if !g.isValidRepo(repo) {
+		return nil, repository.ErrInvalidName
+	}
+
+	if err := g.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	archiveURL, resp, err := g.repositoriesService.GetArchiveLink(ctx, g.owner, repo, github.Zipball, &github.RepositoryContentGetOptions{Ref: ref}, true)
+	g.observeRateLimit(resp, err)
+	if err != nil {
+		return nil, classifyError(resp, err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, archiveURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient := g.httpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading archive: unexpected status code %d", response.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(response.Body, modzip.MaxZipFile+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > modzip.MaxZipFile {
+		return nil, errors.New("downloading archive: archive too large")
+	}
+
+	return data, nil
+}
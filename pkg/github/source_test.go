@@ -0,0 +1,310 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"github.com/google/go-github/v52/github"
+	"go.eigsys.de/masquerade/pkg/repository"
+	"golang.org/x/time/rate"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGitHub_Private(t *testing.T) {
+	tests := []struct {
+		name                string
+		repositoriesService RepositoriesService
+		repo                string
+		want                bool
+		wantErr             bool
+	}{
+		{
+			name:                "public",
+			repositoriesService: &mockRepositoriesService{getRepository: &github.Repository{Private: github.Bool(false)}},
+			repo:                "the-repo",
+		},
+		{
+			name:                "private",
+			repositoriesService: &mockRepositoriesService{getRepository: &github.Repository{Private: github.Bool(true)}},
+			repo:                "the-repo",
+			want:                true,
+		},
+		{
+			name:                "error",
+			repositoriesService: &mockRepositoriesService{getError: errors.New("error")},
+			repo:                "the-repo",
+			wantErr:             true,
+		},
+		{
+			name:                "invalid-repo",
+			repositoriesService: &mockRepositoriesService{},
+			repo:                "/",
+			wantErr:             true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New(tt.repositoriesService, rate.NewLimiter(rate.Inf, 0), "the-owner")
+			got, err := g.Private(context.Background(), tt.repo)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Private() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Private() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitHub_Tags(t *testing.T) {
+	tests := []struct {
+		name                string
+		repositoriesService RepositoriesService
+		repo                string
+		want                []string
+		wantErr             bool
+		wantErrResult       error
+	}{
+		{
+			name: "paginated",
+			repositoriesService: &mockRepositoriesService{listTagsPages: [][]*github.RepositoryTag{
+				{{Name: github.String("v1.0.0")}, {Name: github.String("v1.1.0")}},
+				{{Name: github.String("v2.0.0")}},
+			}},
+			repo: "the-repo",
+			want: []string{"v1.0.0", "v1.1.0", "v2.0.0"},
+		},
+		{
+			name:                "empty",
+			repositoriesService: &mockRepositoriesService{listTagsPages: [][]*github.RepositoryTag{{}}},
+			repo:                "the-repo",
+			want:                []string{},
+		},
+		{
+			name: "not-found",
+			repositoriesService: &mockRepositoriesService{
+				listTagsResponse: &github.Response{Response: &http.Response{StatusCode: http.StatusNotFound}},
+				listTagsError:    errors.New("not found"),
+			},
+			repo:          "the-repo",
+			wantErr:       true,
+			wantErrResult: repository.ErrNotFound,
+		},
+		{
+			name:                "error",
+			repositoriesService: &mockRepositoriesService{listTagsError: errors.New("error")},
+			repo:                "the-repo",
+			wantErr:             true,
+		},
+		{
+			name:                "invalid-repo",
+			repositoriesService: &mockRepositoriesService{},
+			repo:                "/",
+			wantErr:             true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New(tt.repositoriesService, rate.NewLimiter(rate.Inf, 0), "the-owner")
+			got, err := g.Tags(context.Background(), tt.repo)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Tags() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErrResult != nil && !errors.Is(err, tt.wantErrResult) {
+				t.Errorf("Tags() error = %v, wantErrResult %v", err, tt.wantErrResult)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tags() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitHub_CommitTime(t *testing.T) {
+	date := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600))
+	tests := []struct {
+		name                string
+		repositoriesService RepositoriesService
+		want                time.Time
+		wantErr             bool
+		wantErrResult       error
+	}{
+		{
+			name: "ok",
+			repositoriesService: &mockRepositoriesService{getCommitCommit: &github.RepositoryCommit{
+				Commit: &github.Commit{Committer: &github.CommitAuthor{Date: &github.Timestamp{Time: date}}},
+			}},
+			want: date.UTC(),
+		},
+		{
+			name: "unknown-ref",
+			repositoriesService: &mockRepositoriesService{
+				getCommitResponse: &github.Response{Response: &http.Response{StatusCode: http.StatusUnprocessableEntity}},
+				getCommitError:    errors.New("no commit found"),
+			},
+			wantErr:       true,
+			wantErrResult: repository.ErrNotFound,
+		},
+		{
+			name:                "error",
+			repositoriesService: &mockRepositoriesService{getCommitError: errors.New("error")},
+			wantErr:             true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New(tt.repositoriesService, rate.NewLimiter(rate.Inf, 0), "the-owner")
+			got, err := g.CommitTime(context.Background(), "the-repo", "v1.0.0")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CommitTime() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErrResult != nil && !errors.Is(err, tt.wantErrResult) {
+				t.Errorf("CommitTime() error = %v, wantErrResult %v", err, tt.wantErrResult)
+				return
+			}
+			if !got.Equal(tt.want) || got.Location() != tt.want.Location() {
+				t.Errorf("CommitTime() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitHub_File(t *testing.T) {
+	tests := []struct {
+		name                string
+		repositoriesService RepositoriesService
+		want                []byte
+		wantErr             bool
+		wantErrResult       error
+	}{
+		{
+			name: "ok",
+			repositoriesService: &mockRepositoriesService{getContentsFiles: map[string]*github.RepositoryContent{
+				"go.mod": {Content: github.String("module go.example.com/the-repo\n")},
+			}},
+			want: []byte("module go.example.com/the-repo\n"),
+		},
+		{
+			name:                "not-found",
+			repositoriesService: &mockRepositoriesService{getContentsFiles: map[string]*github.RepositoryContent{}},
+			wantErr:             true,
+			wantErrResult:       repository.ErrNotFound,
+		},
+		{
+			name:                "directory",
+			repositoriesService: &mockRepositoriesService{getContentsDirectory: []*github.RepositoryContent{}},
+			wantErr:             true,
+			wantErrResult:       repository.ErrNotFound,
+		},
+		{
+			name:                "error",
+			repositoriesService: &mockRepositoriesService{getContentsError: errors.New("error")},
+			wantErr:             true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New(tt.repositoriesService, rate.NewLimiter(rate.Inf, 0), "the-owner")
+			got, err := g.File(context.Background(), "the-repo", "v1.0.0", "go.mod")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("File() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErrResult != nil && !errors.Is(err, tt.wantErrResult) {
+				t.Errorf("File() error = %v, wantErrResult %v", err, tt.wantErrResult)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("File() got = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitHub_Archive(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		if request.URL.Path != "/archive.zip" {
+			http.NotFound(response, request)
+			return
+		}
+
+		_, _ = response.Write([]byte("archive"))
+	}))
+	defer server.Close()
+
+	archiveURL, _ := url.Parse(server.URL + "/archive.zip")
+	missingURL, _ := url.Parse(server.URL + "/missing.zip")
+
+	tests := []struct {
+		name                string
+		repositoriesService RepositoriesService
+		want                []byte
+		wantErr             bool
+		wantErrResult       error
+	}{
+		{
+			name:                "ok",
+			repositoriesService: &mockRepositoriesService{getArchiveLinkURL: archiveURL},
+			want:                []byte("archive"),
+		},
+		{
+			name: "not-found",
+			repositoriesService: &mockRepositoriesService{
+				getArchiveLinkResponse: &github.Response{Response: &http.Response{StatusCode: http.StatusNotFound}},
+				getArchiveLinkError:    errors.New("not found"),
+			},
+			wantErr:       true,
+			wantErrResult: repository.ErrNotFound,
+		},
+		{
+			name:                "link-error",
+			repositoriesService: &mockRepositoriesService{getArchiveLinkError: errors.New("error")},
+			wantErr:             true,
+		},
+		{
+			name:                "download-error",
+			repositoriesService: &mockRepositoriesService{getArchiveLinkURL: missingURL},
+			wantErr:             true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New(tt.repositoriesService, rate.NewLimiter(rate.Inf, 0), "the-owner", WithHTTPClient(server.Client()))
+			got, err := g.Archive(context.Background(), "the-repo", "v1.0.0")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Archive() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErrResult != nil && !errors.Is(err, tt.wantErrResult) {
+				t.Errorf("Archive() error = %v, wantErrResult %v", err, tt.wantErrResult)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Archive() got = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitHub_invalidRepo(t *testing.T) {
+	g := New(&mockRepositoriesService{}, rate.NewLimiter(rate.Inf, 0), "the-owner")
+	ctx := context.Background()
+
+	if _, err := g.CommitTime(ctx, "/", "v1.0.0"); err == nil {
+		t.Error("CommitTime() no error")
+	}
+	if _, err := g.File(ctx, "/", "v1.0.0", "go.mod"); err == nil {
+		t.Error("File() no error")
+	}
+	if _, err := g.Archive(ctx, "/", "v1.0.0"); err == nil {
+		t.Error("Archive() no error")
+	}
+}
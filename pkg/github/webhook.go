@@ -0,0 +1,135 @@
+package github
+
+import (
+	"errors"
+	"github.com/google/go-github/v52/github"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxPayloadSize is the maximum size of webhook payloads sent by GitHub.
+const maxPayloadSize = 25 << 20
+
+const signatureHeader = "X-Hub-Signature-256"
+
+type Invalidator interface {
+	Invalidate(module string) (int, error)
+}
+
+type WebhookHandler struct {
+	secret      []byte
+	owner       string
+	invalidator Invalidator
+}
+
+func NewWebhookHandler(secret []byte, owner string, invalidator Invalidator) *WebhookHandler {
+	return &WebhookHandler{
+		secret:      secret,
+		owner:       owner,
+		invalidator: invalidator,
+	}
+}
+
+func (h *WebhookHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	payload, err := h.validatePayload(response, request)
+	if maxBytesErr := (*http.MaxBytesError)(nil); errors.As(err, &maxBytesErr) {
+		log.Printf("webhook: invalid payload: %s", err)
+		http.Error(response, "request entity too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		log.Printf("webhook: invalid payload: %s", err)
+		http.Error(response, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	event, err := github.ParseWebHook(github.WebHookType(request), payload)
+	if err != nil {
+		log.Printf("webhook: unsupported event: %s", err)
+		response.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	for _, repo := range h.affectedRepos(event) {
+		purged, err := h.invalidator.Invalidate(repo)
+		if err != nil {
+			log.Printf("webhook: invalidating %q: %s", repo, err)
+			http.Error(response, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("webhook: %s event (delivery %s) purged %d cache entries of %q", github.WebHookType(request), github.DeliveryID(request), purged, repo)
+	}
+
+	response.WriteHeader(http.StatusNoContent)
+}
+
+// validatePayload returns the payload of request, if it's signed with SHA-256. The legacy SHA-1 signature isn't
+// accepted, so that requests can't be downgraded to it.
+func (h *WebhookHandler) validatePayload(response http.ResponseWriter, request *http.Request) ([]byte, error) {
+	signature := request.Header.Get(signatureHeader)
+	if !strings.HasPrefix(signature, "sha256=") {
+		return nil, errors.New("missing " + signatureHeader + " header")
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(response, request.Body, maxPayloadSize))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := github.ValidateSignature(signature, body, h.secret); err != nil {
+		return nil, err
+	}
+
+	contentType, _, err := mime.ParseMediaType(request.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+
+	switch contentType {
+	case "application/json":
+		return body, nil
+	case "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, err
+		}
+
+		return []byte(form.Get("payload")), nil
+	default:
+		return nil, errors.New("unsupported content type " + contentType)
+	}
+}
+
+func (h *WebhookHandler) affectedRepos(event any) []string {
+	switch event := event.(type) {
+	case *github.RepositoryEvent:
+		transferred := event.GetAction() == "transferred"
+		if !transferred && !h.isOwner(event.GetRepo().GetOwner().GetLogin()) {
+			return nil
+		}
+
+		repos := []string{event.GetRepo().GetName()}
+		if from := event.GetChanges().GetRepo().GetName().GetFrom(); from != "" {
+			repos = append(repos, from)
+		}
+
+		return repos
+	case *github.PushEvent:
+		if !h.isOwner(event.GetRepo().GetOwner().GetLogin()) {
+			return nil
+		}
+
+		return []string{event.GetRepo().GetName()}
+	default:
+		return nil
+	}
+}
+
+func (h *WebhookHandler) isOwner(login string) bool {
+	return strings.EqualFold(login, h.owner)
+}
@@ -0,0 +1,239 @@
+package github
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type mockInvalidator struct {
+	invalidated []string
+	err         error
+}
+
+func (m *mockInvalidator) Invalidate(module string) (int, error) {
+	if m.err != nil {
+		return 0, m.err
+	}
+
+	m.invalidated = append(m.invalidated, module)
+
+	return 1, nil
+}
+
+func newWebhookRequest(t *testing.T, event, file string, secret []byte) *http.Request {
+	t.Helper()
+
+	payload, err := os.ReadFile(filepath.Join("testdata", file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+
+	request := httptest.NewRequest(http.MethodPost, "/.internal/webhooks/github", bytes.NewReader(payload))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-GitHub-Event", event)
+	request.Header.Set("X-GitHub-Delivery", "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	request.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	return request
+}
+
+func TestWebhookHandler_ServeHTTP(t *testing.T) {
+	secret := []byte("the-secret")
+	tests := []struct {
+		name            string
+		event           string
+		file            string
+		signingSecret   []byte
+		invalidatorErr  error
+		wantCode        int
+		wantInvalidated []string
+	}{
+		{
+			name:            "repository-renamed",
+			event:           "repository",
+			file:            "repository_renamed.json",
+			signingSecret:   secret,
+			wantCode:        http.StatusNoContent,
+			wantInvalidated: []string{"new-repo", "old-repo"},
+		},
+		{
+			name:            "repository-created",
+			event:           "repository",
+			file:            "repository_created.json",
+			signingSecret:   secret,
+			wantCode:        http.StatusNoContent,
+			wantInvalidated: []string{"the-repo"},
+		},
+		{
+			name:          "repository-other-owner",
+			event:         "repository",
+			file:          "repository_other_owner.json",
+			signingSecret: secret,
+			wantCode:      http.StatusNoContent,
+		},
+		{
+			name:            "push",
+			event:           "push",
+			file:            "push.json",
+			signingSecret:   secret,
+			wantCode:        http.StatusNoContent,
+			wantInvalidated: []string{"the-repo"},
+		},
+		{
+			name:          "ping",
+			event:         "ping",
+			file:          "ping.json",
+			signingSecret: secret,
+			wantCode:      http.StatusNoContent,
+		},
+		{
+			name:          "unsupported-event",
+			event:         "unknown",
+			file:          "ping.json",
+			signingSecret: secret,
+			wantCode:      http.StatusNoContent,
+		},
+		{
+			name:          "invalid-signature",
+			event:         "push",
+			file:          "push.json",
+			signingSecret: []byte("wrong-secret"),
+			wantCode:      http.StatusUnauthorized,
+		},
+		{
+			name:           "invalidator-error",
+			event:          "push",
+			file:           "push.json",
+			signingSecret:  secret,
+			invalidatorErr: errors.New("error"),
+			wantCode:       http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invalidator := &mockInvalidator{err: tt.invalidatorErr}
+			response := httptest.NewRecorder()
+
+			NewWebhookHandler(secret, "the-owner", invalidator).ServeHTTP(response, newWebhookRequest(t, tt.event, tt.file, tt.signingSecret))
+
+			if response.Code != tt.wantCode {
+				t.Errorf("ServeHTTP() code = %v, want %v", response.Code, tt.wantCode)
+			}
+			if !reflect.DeepEqual(invalidator.invalidated, tt.wantInvalidated) {
+				t.Errorf("ServeHTTP() invalidated = %v, want %v", invalidator.invalidated, tt.wantInvalidated)
+			}
+		})
+	}
+}
+
+func TestWebhookHandler_ServeHTTP_signature(t *testing.T) {
+	secret := []byte("the-secret")
+	payload, err := os.ReadFile(filepath.Join("testdata", "push.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func(header, prefix string, hashFunc func() hash.Hash) func(body []byte) (string, string) {
+		return func(body []byte) (string, string) {
+			mac := hmac.New(hashFunc, secret)
+			mac.Write(body)
+			return header, prefix + hex.EncodeToString(mac.Sum(nil))
+		}
+	}
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		sign        func(body []byte) (string, string)
+		wantCode    int
+	}{
+		{
+			name:        "sha256",
+			contentType: "application/json",
+			body:        payload,
+			sign:        sign("X-Hub-Signature-256", "sha256=", sha256.New),
+			wantCode:    http.StatusNoContent,
+		},
+		{
+			name:        "sha1-downgrade",
+			contentType: "application/json",
+			body:        payload,
+			sign:        sign("X-Hub-Signature", "sha1=", sha1.New),
+			wantCode:    http.StatusUnauthorized,
+		},
+		{
+			name:        "sha1-in-sha256-header",
+			contentType: "application/json",
+			body:        payload,
+			sign:        sign("X-Hub-Signature-256", "sha1=", sha1.New),
+			wantCode:    http.StatusUnauthorized,
+		},
+		{
+			name:        "too-large",
+			contentType: "application/json",
+			body:        append(payload, bytes.Repeat([]byte(" "), maxPayloadSize)...),
+			sign:        sign("X-Hub-Signature-256", "sha256=", sha256.New),
+			wantCode:    http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:        "form",
+			contentType: "application/x-www-form-urlencoded",
+			body:        []byte(url.Values{"payload": {string(payload)}}.Encode()),
+			sign:        sign("X-Hub-Signature-256", "sha256=", sha256.New),
+			wantCode:    http.StatusNoContent,
+		},
+		{
+			name:        "unsupported-content-type",
+			contentType: "text/plain",
+			body:        payload,
+			sign:        sign("X-Hub-Signature-256", "sha256=", sha256.New),
+			wantCode:    http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/.internal/webhooks/github", bytes.NewReader(tt.body))
+			request.Header.Set("Content-Type", tt.contentType)
+			request.Header.Set("X-GitHub-Event", "push")
+			request.Header.Set(tt.sign(tt.body))
+			invalidator := &mockInvalidator{}
+			response := httptest.NewRecorder()
+
+			NewWebhookHandler(secret, "the-owner", invalidator).ServeHTTP(response, request)
+
+			if response.Code != tt.wantCode {
+				t.Errorf("ServeHTTP() code = %v, want %v", response.Code, tt.wantCode)
+			}
+			if wantInvalidated := tt.wantCode == http.StatusNoContent; (len(invalidator.invalidated) == 1) != wantInvalidated {
+				t.Errorf("ServeHTTP() invalidated = %v", invalidator.invalidated)
+			}
+		})
+	}
+}
+
+func TestWebhookHandler_ServeHTTP_missingSignature(t *testing.T) {
+	invalidator := &mockInvalidator{}
+	request := newWebhookRequest(t, "push", "push.json", []byte("the-secret"))
+	request.Header.Del("X-Hub-Signature-256")
+	response := httptest.NewRecorder()
+
+	NewWebhookHandler([]byte("the-secret"), "the-owner", invalidator).ServeHTTP(response, request)
+
+	if response.Code != http.StatusUnauthorized || len(invalidator.invalidated) != 0 {
+		t.Errorf("ServeHTTP() code = %v, invalidated = %v", response.Code, invalidator.invalidated)
+	}
+}
@@ -0,0 +1,49 @@
+package gitlab
+
+import (
+	"context"
+	"go.eigsys.de/masquerade/pkg/internal/apiclient"
+	"net/http"
+	"net/url"
+)
+
+const DefaultBaseURL = "https://gitlab.com"
+
+type Project struct {
+	ID                int64  `json:"id"`
+	Name              string `json:"name"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	WebURL            string `json:"web_url"`
+	HTTPURLToRepo     string `json:"http_url_to_repo"`
+	DefaultBranch     string `json:"default_branch"`
+}
+
+type ErrorResponse = apiclient.ErrorResponse
+
+type Client struct {
+	api *apiclient.Client
+}
+
+func NewClient(baseURL string, httpClient *http.Client, token string) (*Client, error) {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	api, err := apiclient.New(baseURL, httpClient, "PRIVATE-TOKEN", token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{api: api}, nil
+}
+
+func (c *Client) GetProject(ctx context.Context, pid string) (*Project, *http.Response, error) {
+	project := &Project{}
+
+	resp, err := c.api.Get(ctx, "/api/v4/projects/"+url.PathEscape(pid), project)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return project, resp, nil
+}
@@ -0,0 +1,155 @@
+package gitlab
+
+import (
+	"context"
+	"go.eigsys.de/masquerade/pkg/internal/apiclient/apiclienttest"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	return apiclienttest.NewServer(t, "/api/v4/projects/", "PRIVATE-TOKEN", map[string]string{
+		"/api/v4/projects/the-group%2Fthe-project":                `{"id":1,"name":"the-project","path_with_namespace":"the-group/the-project","web_url":"https://gitlab.example.com/the-group/the-project","http_url_to_repo":"https://gitlab.example.com/the-group/the-project.git","default_branch":"main"}`,
+		"/api/v4/projects/the-group%2Fthe-subgroup%2Fthe-project": `{"id":2,"name":"the-project","path_with_namespace":"the-group/the-subgroup/the-project","web_url":"https://gitlab.example.com/the-group/the-subgroup/the-project","http_url_to_repo":"https://gitlab.example.com/the-group/the-subgroup/the-project.git","default_branch":"main"}`,
+		"/api/v4/projects/the-group%2Finvalid-json":               `{`,
+	})
+}
+
+func TestNewClient(t *testing.T) {
+	tests := []struct {
+		name        string
+		baseURL     string
+		wantBaseURL string
+		wantErr     bool
+	}{
+		{
+			name:        "default",
+			baseURL:     "",
+			wantBaseURL: DefaultBaseURL,
+		},
+		{
+			name:        "trailing-slash",
+			baseURL:     "https://gitlab.example.com/",
+			wantBaseURL: "https://gitlab.example.com",
+		},
+		{
+			name:        "path-prefix",
+			baseURL:     "https://example.com/gitlab",
+			wantBaseURL: "https://example.com/gitlab",
+		},
+		{
+			name:    "missing-scheme",
+			baseURL: "gitlab.example.com",
+			wantErr: true,
+		},
+		{
+			name:    "invalid-url",
+			baseURL: "https://gitlab.example.com/%zz",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewClient(tt.baseURL, nil, "")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewClient() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && got.api.BaseURL() != tt.wantBaseURL {
+				t.Errorf("NewClient() baseURL = %v, want %v", got.api.BaseURL(), tt.wantBaseURL)
+			}
+		})
+	}
+}
+
+func TestClient_GetProject(t *testing.T) {
+	server := newTestServer(t)
+	tests := []struct {
+		name           string
+		token          string
+		pid            string
+		want           *Project
+		wantStatusCode int
+		wantErr        bool
+	}{
+		{
+			name: "ok",
+			pid:  "the-group/the-project",
+			want: &Project{
+				ID:                1,
+				Name:              "the-project",
+				PathWithNamespace: "the-group/the-project",
+				WebURL:            "https://gitlab.example.com/the-group/the-project",
+				HTTPURLToRepo:     "https://gitlab.example.com/the-group/the-project.git",
+				DefaultBranch:     "main",
+			},
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name: "nested-subgroup",
+			pid:  "the-group/the-subgroup/the-project",
+			want: &Project{
+				ID:                2,
+				Name:              "the-project",
+				PathWithNamespace: "the-group/the-subgroup/the-project",
+				WebURL:            "https://gitlab.example.com/the-group/the-subgroup/the-project",
+				HTTPURLToRepo:     "https://gitlab.example.com/the-group/the-subgroup/the-project.git",
+				DefaultBranch:     "main",
+			},
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "not-found",
+			pid:            "the-group/unknown",
+			wantStatusCode: http.StatusNotFound,
+			wantErr:        true,
+		},
+		{
+			name:           "unauthorized",
+			token:          apiclienttest.InvalidToken,
+			pid:            "the-group/the-project",
+			wantStatusCode: http.StatusUnauthorized,
+			wantErr:        true,
+		},
+		{
+			name:           "invalid-json",
+			pid:            "the-group/invalid-json",
+			wantStatusCode: http.StatusOK,
+			wantErr:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewClient(server.URL, server.Client(), tt.token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, resp, err := c.GetProject(context.Background(), tt.pid)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetProject() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if resp == nil || resp.StatusCode != tt.wantStatusCode {
+				t.Errorf("GetProject() resp = %v, wantStatusCode %v", resp, tt.wantStatusCode)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetProject() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_GetProject_error(t *testing.T) {
+	c, err := NewClient("http://127.0.0.1:0", nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := c.GetProject(context.Background(), "the-group/the-project"); err == nil {
+		t.Error("no error")
+	}
+}
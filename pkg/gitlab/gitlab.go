@@ -0,0 +1,56 @@
+package gitlab
+
+import (
+	"context"
+	"go.eigsys.de/masquerade/pkg/internal/apiclient"
+	"go.eigsys.de/masquerade/pkg/repository"
+	"golang.org/x/time/rate"
+	"net/http"
+	"path"
+	"regexp"
+)
+
+var projectRegexp = regexp.MustCompile(`^[a-zA-Z0-9-_.]{1,255}$`)
+
+type ProjectsService interface {
+	GetProject(ctx context.Context, pid string) (*Project, *http.Response, error)
+}
+
+type GitLab struct {
+	projectsService ProjectsService
+	limiter         *rate.Limiter
+	group           string
+}
+
+func New(projectsService ProjectsService, limiter *rate.Limiter, group string) *GitLab {
+	return &GitLab{
+		projectsService: projectsService,
+		limiter:         limiter,
+		group:           group,
+	}
+}
+
+func (g *GitLab) Type() string {
+	return "git"
+}
+
+func (g *GitLab) isValidProject(project string) bool {
+	return projectRegexp.MatchString(project)
+}
+
+func (g *GitLab) Fetch(ctx context.Context, repo string) (repository.Repository, error) {
+	if !g.isValidProject(repo) {
+		return nil, repository.ErrInvalidName
+	}
+
+	if err := g.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	data, resp, err := g.projectsService.GetProject(ctx, path.Join(g.group, repo))
+	if err != nil {
+		return nil, apiclient.ClassifyError(resp, err)
+	}
+
+	return &Repository{project: data}, nil
+}
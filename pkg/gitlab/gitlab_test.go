@@ -0,0 +1,225 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"go.eigsys.de/masquerade/pkg/internal/apiclient/apiclienttest"
+	"go.eigsys.de/masquerade/pkg/repository"
+	"golang.org/x/time/rate"
+	"reflect"
+	"testing"
+)
+
+func TestGitLab_Type(t *testing.T) {
+	g := &GitLab{}
+
+	if g.Type() != "git" {
+		t.Errorf("wrong type")
+	}
+}
+
+func TestGitLab_isValidProject(t *testing.T) {
+	type args struct {
+		project string
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{
+			name: "valid-1-character",
+			args: args{project: "a"},
+			want: true,
+		},
+		{
+			name: "valid-special-characters",
+			args: args{project: "-_."},
+			want: true,
+		},
+		{
+			name: "invalid-0-characters",
+			args: args{project: ""},
+			want: false,
+		},
+		{
+			name: "invalid-character",
+			args: args{project: "/"},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &GitLab{}
+			if got := g.isValidProject(tt.args.project); got != tt.want {
+				t.Errorf("isValidProject() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitLab_Fetch(t *testing.T) {
+	server := newTestServer(t)
+	client, err := NewClient(server.URL, server.Client(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unauthorizedClient, err := NewClient(server.URL, server.Client(), apiclienttest.InvalidToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type fields struct {
+		projectsService ProjectsService
+		limiter         *rate.Limiter
+		group           string
+	}
+	type args struct {
+		ctx  context.Context
+		repo string
+	}
+	tests := []struct {
+		name          string
+		fields        fields
+		args          args
+		want          repository.Repository
+		wantErr       bool
+		wantErrResult error
+	}{
+		{
+			name: "ok",
+			fields: fields{
+				projectsService: client,
+				limiter:         rate.NewLimiter(rate.Inf, 0),
+				group:           "the-group",
+			},
+			args: args{
+				ctx:  context.Background(),
+				repo: "the-project",
+			},
+			want: &Repository{project: &Project{
+				ID:                1,
+				Name:              "the-project",
+				PathWithNamespace: "the-group/the-project",
+				WebURL:            "https://gitlab.example.com/the-group/the-project",
+				HTTPURLToRepo:     "https://gitlab.example.com/the-group/the-project.git",
+				DefaultBranch:     "main",
+			}},
+		},
+		{
+			name: "nested-subgroup",
+			fields: fields{
+				projectsService: client,
+				limiter:         rate.NewLimiter(rate.Inf, 0),
+				group:           "the-group/the-subgroup",
+			},
+			args: args{
+				ctx:  context.Background(),
+				repo: "the-project",
+			},
+			want: &Repository{project: &Project{
+				ID:                2,
+				Name:              "the-project",
+				PathWithNamespace: "the-group/the-subgroup/the-project",
+				WebURL:            "https://gitlab.example.com/the-group/the-subgroup/the-project",
+				HTTPURLToRepo:     "https://gitlab.example.com/the-group/the-subgroup/the-project.git",
+				DefaultBranch:     "main",
+			}},
+		},
+		{
+			name: "invalid-repo",
+			args: args{
+				repo: "the/project",
+			},
+			wantErr: true,
+		},
+		{
+			name: "limiter-error",
+			fields: fields{
+				limiter: rate.NewLimiter(0, 0),
+			},
+			args: args{
+				ctx:  context.Background(),
+				repo: "the-project",
+			},
+			wantErr: true,
+		},
+		{
+			name: "project-not-found",
+			fields: fields{
+				projectsService: client,
+				limiter:         rate.NewLimiter(rate.Inf, 0),
+				group:           "the-group",
+			},
+			args: args{
+				ctx:  context.Background(),
+				repo: "unknown",
+			},
+			wantErr:       true,
+			wantErrResult: repository.ErrNotFound,
+		},
+		{
+			name: "unauthorized",
+			fields: fields{
+				projectsService: unauthorizedClient,
+				limiter:         rate.NewLimiter(rate.Inf, 0),
+				group:           "the-group",
+			},
+			args: args{
+				ctx:  context.Background(),
+				repo: "the-project",
+			},
+			wantErr:       true,
+			wantErrResult: repository.ErrForbidden,
+		},
+		{
+			name: "generic-error",
+			fields: fields{
+				projectsService: client,
+				limiter:         rate.NewLimiter(rate.Inf, 0),
+				group:           "the-group",
+			},
+			args: args{
+				ctx:  context.Background(),
+				repo: "invalid-json",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &GitLab{
+				projectsService: tt.fields.projectsService,
+				limiter:         tt.fields.limiter,
+				group:           tt.fields.group,
+			}
+			got, err := g.Fetch(tt.args.ctx, tt.args.repo)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Fetch() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErrResult != nil && !errors.Is(err, tt.wantErrResult) {
+				t.Errorf("Fetch() error = %v, wantErrResult %v", err, tt.wantErrResult)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Fetch() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	projectsService := &Client{}
+	limiter := rate.NewLimiter(0, 0)
+	group := "the-group"
+	g := New(projectsService, limiter, group)
+	want := &GitLab{
+		projectsService: projectsService,
+		limiter:         limiter,
+		group:           group,
+	}
+
+	if !reflect.DeepEqual(g, want) {
+		t.Errorf("unexpected result")
+	}
+}
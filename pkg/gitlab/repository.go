@@ -0,0 +1,57 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"go.eigsys.de/masquerade/pkg/repository"
+)
+
+const (
+	sourceDirectoryPath = "-/tree"
+	sourceFilePath      = "-/blob"
+)
+
+type Repository struct {
+	project *Project
+}
+
+type repositoryData struct {
+	Project *Project `json:"project"`
+}
+
+func (r *Repository) MarshalBinary() ([]byte, error) {
+	return json.Marshal(&repositoryData{Project: r.project})
+}
+
+func (r *Repository) UnmarshalBinary(data []byte) error {
+	repositoryData := &repositoryData{}
+	if err := json.Unmarshal(data, repositoryData); err != nil {
+		return err
+	}
+
+	r.project = repositoryData.Project
+
+	return nil
+}
+
+func (r *Repository) GetRepoRoot() string {
+	if r.project == nil {
+		return ""
+	}
+	return r.project.HTTPURLToRepo
+}
+
+func (r *Repository) GetProjectWebsiteOrFallback(fallback string) string {
+	if r.project == nil || r.project.WebURL == "" {
+		return fallback
+	}
+
+	return r.project.WebURL
+}
+
+func (r *Repository) GetSourceURLs(dir string) repository.SourceURLs {
+	if r.project == nil {
+		return repository.SourceURLs{}
+	}
+
+	return repository.NewSourceURLs(r.project.WebURL, r.project.DefaultBranch, dir, sourceDirectoryPath, sourceFilePath)
+}
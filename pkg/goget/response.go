@@ -6,7 +6,10 @@ import (
 )
 
 const bodyTemplate = `<head>
-<meta name="go-import" content="{{.ImportPrefix}} {{.VCS}} {{.RepoRoot}}">
+<meta name="go-import" content="{{.ImportPrefix}} {{.VCS}} {{.RepoRoot}}{{with .Subdirectory}} {{.}}{{end}}">
+{{- with .SourceHome}}
+<meta name="go-source" content="{{$.ImportPrefix}} {{.}} {{$.SourceDirectory}} {{$.SourceFile}}">
+{{- end}}
 <meta http-equiv="refresh" content="0;URL='{{.ProjectWebsite}}'">
 <body>
 Redirecting you to the <a href="{{.ProjectWebsite}}">project website</a>...`
@@ -14,18 +17,35 @@ Redirecting you to the <a href="{{.ProjectWebsite}}">project website</a>...`
 var body = template.Must(template.New("body").Parse(bodyTemplate))
 
 type TemplateData struct {
-	ImportPrefix   string
-	VCS            string
-	RepoRoot       string
-	ProjectWebsite string
+	ImportPrefix    string
+	VCS             string
+	RepoRoot        string
+	Subdirectory    string
+	SourceHome      string
+	SourceDirectory string
+	SourceFile      string
+	ProjectWebsite  string
 }
 
-type ResponseBody struct{}
+type ResponseBody struct {
+	body *template.Template
+}
 
 func New() *ResponseBody {
-	return &ResponseBody{}
+	return &ResponseBody{body: body}
+}
+
+// NewWithTemplate uses a custom HTML template, which receives the TemplateData and has to render at least the
+// go-import meta tag.
+func NewWithTemplate(text string) (*ResponseBody, error) {
+	custom, err := template.New("body").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResponseBody{body: custom}, nil
 }
 
 func (r *ResponseBody) Build(writer io.Writer, data *TemplateData) error {
-	return body.Execute(writer, data)
+	return r.body.Execute(writer, data)
 }
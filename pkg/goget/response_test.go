@@ -29,6 +29,59 @@ Redirecting you to the <a href="project-website">project website</a>...`)
 	}
 }
 
+func TestResponseBody_Build_subdirectory(t *testing.T) {
+	data := &TemplateData{
+		ImportPrefix:   "import-prefix/sub",
+		VCS:            "vcs",
+		RepoRoot:       "repo-root",
+		Subdirectory:   "sub",
+		ProjectWebsite: "project-website",
+	}
+	writer := &bytes.Buffer{}
+	want := []byte(`<head>
+<meta name="go-import" content="import-prefix/sub vcs repo-root sub">
+<meta http-equiv="refresh" content="0;URL='project-website'">
+<body>
+Redirecting you to the <a href="project-website">project website</a>...`)
+	response := New()
+
+	if err := response.Build(writer, data); err != nil {
+		t.Error("unexpected error")
+	}
+
+	if !bytes.Equal(writer.Bytes(), want) {
+		t.Error("wrong result")
+	}
+}
+
+func TestResponseBody_Build_source(t *testing.T) {
+	data := &TemplateData{
+		ImportPrefix:    "import-prefix",
+		VCS:             "vcs",
+		RepoRoot:        "repo-root",
+		SourceHome:      "https://example.com/repo",
+		SourceDirectory: "https://example.com/repo/tree/main{/dir}",
+		SourceFile:      "https://example.com/repo/blob/main{/dir}/{file}#L{line}",
+		ProjectWebsite:  "project-website",
+	}
+	writer := &bytes.Buffer{}
+	want := []byte(`<head>
+<meta name="go-import" content="import-prefix vcs repo-root">
+<meta name="go-source" content="import-prefix https://example.com/repo https://example.com/repo/tree/main{/dir} https://example.com/repo/blob/main{/dir}/{file}#L{line}">
+<meta http-equiv="refresh" content="0;URL='project-website'">
+<body>
+Redirecting you to the <a href="project-website">project website</a>...`)
+	response := New()
+
+	if err := response.Build(writer, data); err != nil {
+		t.Error("unexpected error")
+	}
+
+	if !bytes.Equal(writer.Bytes(), want) {
+		t.Errorf("wrong result: %s", writer.Bytes())
+	}
+}
+
 func TestResponseBody_Build_error(t *testing.T) {
 	writer := &bytes.Buffer{}
 	response := New()
@@ -37,3 +90,28 @@ func TestResponseBody_Build_error(t *testing.T) {
 		t.Error("no error")
 	}
 }
+
+func TestNewWithTemplate(t *testing.T) {
+	data := &TemplateData{ImportPrefix: "import-prefix", VCS: "vcs", RepoRoot: "repo-root"}
+	writer := &bytes.Buffer{}
+	want := []byte(`<meta name="go-import" content="import-prefix vcs repo-root">`)
+
+	response, err := NewWithTemplate(`<meta name="go-import" content="{{.ImportPrefix}} {{.VCS}} {{.RepoRoot}}">`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := response.Build(writer, data); err != nil {
+		t.Error("unexpected error")
+	}
+
+	if !bytes.Equal(writer.Bytes(), want) {
+		t.Errorf("wrong result: %s", writer.Bytes())
+	}
+}
+
+func TestNewWithTemplate_error(t *testing.T) {
+	if _, err := NewWithTemplate("{{.ImportPrefix"); err == nil {
+		t.Error("no error")
+	}
+}
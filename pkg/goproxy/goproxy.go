@@ -0,0 +1,470 @@
+package goproxy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.eigsys.de/masquerade/pkg/cache"
+	"go.eigsys.de/masquerade/pkg/repository"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+	modzip "golang.org/x/mod/zip"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	sourceTimeout = 30 * time.Second
+
+	// maxConcurrentZips limits the memory used for serving module zips, each of which holds the repository archive
+	// and the module zip.
+	maxConcurrentZips = 4
+)
+
+// errInvalidRequest is answered like a missing module, so that the go command falls back to the next proxy.
+var errInvalidRequest = fmt.Errorf("invalid proxy request: %w", repository.ErrNotFound)
+
+type Source interface {
+	// Private reports whether the repository is private, so that its modules are only fetched directly.
+	Private(ctx context.Context, repo string) (bool, error)
+	Tags(ctx context.Context, repo string) ([]string, error)
+	CommitTime(ctx context.Context, repo, ref string) (time.Time, error)
+	File(ctx context.Context, repo, ref, filePath string) ([]byte, error)
+	Archive(ctx context.Context, repo, ref string) ([]byte, error)
+}
+
+type Memoizer interface {
+	Memoize(key string, fn func() (any, error)) (any, error, bool)
+}
+
+type Info struct {
+	Version string
+	Time    time.Time
+}
+
+// ErrorHandler writes the response for a request that failed with err.
+type ErrorHandler func(response http.ResponseWriter, request *http.Request, err error)
+
+type Option func(p *Proxy)
+
+// WithErrorHandler replaces the default error responses, which only distinguish missing modules from upstream
+// errors.
+func WithErrorHandler(errorHandler ErrorHandler) Option {
+	return func(p *Proxy) {
+		p.errorHandler = errorHandler
+	}
+}
+
+type Proxy struct {
+	source       Source
+	cache        Memoizer
+	packageHost  string
+	errorHandler ErrorHandler
+	zips         chan struct{}
+}
+
+func New(source Source, cache Memoizer, packageHost string, options ...Option) *Proxy {
+	p := &Proxy{
+		source:      source,
+		cache:       cache,
+		packageHost: packageHost,
+		zips:        make(chan struct{}, maxConcurrentZips),
+	}
+
+	for _, option := range options {
+		option(p)
+	}
+
+	return p
+}
+
+type request struct {
+	modulePath string
+	repo       string
+	dir        string
+	pathMajor  string
+	action     string
+	version    string
+}
+
+func (r *request) tag(version string) string {
+	if r.dir == "" {
+		return version
+	}
+
+	return r.dir + "/" + version
+}
+
+func IsProxyRequest(urlPath string) bool {
+	return strings.Contains(urlPath, "/@v/") || strings.HasSuffix(urlPath, "/@latest")
+}
+
+func (p *Proxy) parseRequest(urlPath string) (*request, error) {
+	r := &request{}
+	escapedPath := strings.TrimPrefix(urlPath, "/")
+
+	if strings.HasSuffix(escapedPath, "/@latest") {
+		escapedPath = strings.TrimSuffix(escapedPath, "/@latest")
+		r.action = "latest"
+	} else {
+		index := strings.LastIndex(escapedPath, "/@v/")
+		if index < 0 {
+			return nil, errInvalidRequest
+		}
+
+		file := escapedPath[index+len("/@v/"):]
+		escapedPath = escapedPath[:index]
+
+		if file == "list" {
+			r.action = "list"
+		} else {
+			extension := path.Ext(file)
+			switch extension {
+			case ".info", ".mod", ".zip":
+			default:
+				return nil, errInvalidRequest
+			}
+
+			version, err := module.UnescapeVersion(strings.TrimSuffix(file, extension))
+			if err != nil {
+				return nil, errInvalidRequest
+			}
+
+			r.action = strings.TrimPrefix(extension, ".")
+			r.version = version
+		}
+	}
+
+	modulePath, err := module.UnescapePath(escapedPath)
+	if err != nil {
+		return nil, errInvalidRequest
+	}
+
+	prefix, pathMajor, ok := module.SplitPathVersion(modulePath)
+	if !ok || !strings.HasPrefix(prefix, p.packageHost+"/") {
+		return nil, errInvalidRequest
+	}
+
+	repo, dir, _ := strings.Cut(strings.TrimPrefix(prefix, p.packageHost+"/"), "/")
+	if repo == "" {
+		return nil, errInvalidRequest
+	}
+
+	r.modulePath = modulePath
+	r.repo = repo
+	r.dir = dir
+	r.pathMajor = pathMajor
+
+	if r.version != "" && !p.isValidVersion(r, r.version) {
+		return nil, errInvalidRequest
+	}
+
+	return r, nil
+}
+
+func (p *Proxy) isValidVersion(r *request, version string) bool {
+	return semver.IsValid(version) && semver.Canonical(version) == version && module.CheckPathMajor(version, r.pathMajor) == nil
+}
+
+// checkPublic refuses private repositories, because the proxy doesn't authenticate its clients.
+func (p *Proxy) checkPublic(ctx context.Context, r *request) error {
+	private, err, _ := p.cache.Memoize(cache.Key(r.repo, "private"), cache.Detach(ctx, sourceTimeout, func(ctx context.Context) (any, error) {
+		return p.source.Private(ctx, r.repo)
+	}))
+	if err != nil {
+		return err
+	}
+
+	if private.(bool) {
+		return fmt.Errorf("%w: %s is private", repository.ErrNotFound, r.repo)
+	}
+
+	return nil
+}
+
+func (p *Proxy) versions(ctx context.Context, r *request) ([]string, error) {
+	tags, err, _ := p.cache.Memoize(cache.Key(r.repo, "tags"), cache.Detach(ctx, sourceTimeout, func(ctx context.Context) (any, error) {
+		return p.source.Tags(ctx, r.repo)
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []string{}
+	tagPrefix := r.tag("")
+
+	for _, tag := range tags.([]string) {
+		if version, ok := strings.CutPrefix(tag, tagPrefix); ok && p.isValidVersion(r, version) {
+			versions = append(versions, version)
+		}
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return semver.Compare(versions[i], versions[j]) < 0
+	})
+
+	return versions, nil
+}
+
+func (p *Proxy) hasVersion(ctx context.Context, r *request, version string) error {
+	versions, err := p.versions(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range versions {
+		if v == version {
+			return nil
+		}
+	}
+
+	return repository.ErrNotFound
+}
+
+func latestVersion(versions []string) string {
+	for i := len(versions) - 1; i >= 0; i-- {
+		if semver.Prerelease(versions[i]) == "" {
+			return versions[i]
+		}
+	}
+
+	if len(versions) > 0 {
+		return versions[len(versions)-1]
+	}
+
+	return ""
+}
+
+func (p *Proxy) info(ctx context.Context, r *request, version string) (*Info, error) {
+	tag := r.tag(version)
+
+	commitTime, err, _ := p.cache.Memoize(cache.Key(r.repo, "time", tag), cache.Detach(ctx, sourceTimeout, func(ctx context.Context) (any, error) {
+		return p.source.CommitTime(ctx, r.repo, tag)
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Info{Version: version, Time: commitTime.(time.Time)}, nil
+}
+
+func (p *Proxy) moduleDir(ctx context.Context, r *request) (string, []byte, error) {
+	dirs := []string{r.dir}
+	if r.pathMajor != "" {
+		dirs = []string{path.Join(r.dir, strings.TrimPrefix(r.pathMajor, "/")), r.dir}
+	}
+
+	for _, dir := range dirs {
+		goMod, err := p.source.File(ctx, r.repo, r.tag(r.version), path.Join(dir, "go.mod"))
+		if err == nil {
+			return dir, goMod, nil
+		}
+
+		if !errors.Is(err, repository.ErrNotFound) {
+			return "", nil, err
+		}
+	}
+
+	return r.dir, nil, nil
+}
+
+func (p *Proxy) goMod(ctx context.Context, r *request) ([]byte, error) {
+	_, goMod, err := p.moduleDir(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	if goMod == nil {
+		return []byte(fmt.Sprintf("module %s\n", r.modulePath)), nil
+	}
+
+	return goMod, nil
+}
+
+type archiveFile struct {
+	path string
+	file *zip.File
+}
+
+func (f *archiveFile) Path() string {
+	return f.path
+}
+
+func (f *archiveFile) Lstat() (fs.FileInfo, error) {
+	return f.file.FileInfo(), nil
+}
+
+func (f *archiveFile) Open() (io.ReadCloser, error) {
+	return f.file.Open()
+}
+
+func (p *Proxy) zip(ctx context.Context, r *request, writer io.Writer) error {
+	dir, _, err := p.moduleDir(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	archive, err := p.source.Archive(ctx, r.repo, r.tag(r.version))
+	if err != nil {
+		return err
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return err
+	}
+
+	files := []modzip.File{}
+	var rootLicense modzip.File
+	hasLicense := false
+
+	for _, file := range reader.File {
+		// Repository archives contain a single top-level directory, which isn't part of the module.
+		_, filePath, ok := strings.Cut(file.Name, "/")
+		if !ok || file.FileInfo().IsDir() {
+			continue
+		}
+
+		if dir != "" {
+			if filePath == "LICENSE" {
+				rootLicense = &archiveFile{path: filePath, file: file}
+			}
+
+			if filePath, ok = strings.CutPrefix(filePath, dir+"/"); !ok {
+				continue
+			}
+		}
+
+		hasLicense = hasLicense || filePath == "LICENSE"
+		files = append(files, &archiveFile{path: filePath, file: file})
+	}
+
+	// Like the go command, modules in a subdirectory without a license of their own get the one of the repository
+	// root, so that the zip has the same checksum.
+	if !hasLicense && rootLicense != nil {
+		files = append(files, rootLicense)
+	}
+
+	return modzip.Create(writer, module.Version{Path: r.modulePath, Version: r.version}, files)
+}
+
+func (p *Proxy) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	err := p.serve(response, request)
+	if err == nil {
+		return
+	}
+
+	if p.errorHandler != nil {
+		p.errorHandler(response, request, err)
+		return
+	}
+
+	handleError(response, request, err)
+}
+
+func handleError(response http.ResponseWriter, _ *http.Request, err error) {
+	log.Print(err)
+
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(response, "not found", http.StatusNotFound)
+		return
+	}
+
+	http.Error(response, "bad gateway", http.StatusBadGateway)
+}
+
+func (p *Proxy) serve(response http.ResponseWriter, request *http.Request) error {
+	r, err := p.parseRequest(request.URL.Path)
+	if err != nil {
+		return err
+	}
+
+	ctx := request.Context()
+
+	if err := p.checkPublic(ctx, r); err != nil {
+		return err
+	}
+
+	switch r.action {
+	case "list":
+		versions, err := p.versions(ctx, r)
+		if err != nil {
+			return err
+		}
+
+		response.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, version := range versions {
+			_, _ = fmt.Fprintln(response, version)
+		}
+
+		return nil
+	case "latest":
+		versions, err := p.versions(ctx, r)
+		if err != nil {
+			return err
+		}
+
+		version := latestVersion(versions)
+		if version == "" {
+			return repository.ErrNotFound
+		}
+
+		return p.writeInfo(ctx, response, r, version)
+	}
+
+	if err := p.hasVersion(ctx, r, r.version); err != nil {
+		return err
+	}
+
+	switch r.action {
+	case "info":
+		return p.writeInfo(ctx, response, r, r.version)
+	case "mod":
+		goMod, err := p.goMod(ctx, r)
+		if err != nil {
+			return err
+		}
+
+		response.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = response.Write(goMod)
+
+		return nil
+	default:
+		select {
+		case p.zips <- struct{}{}:
+			defer func() { <-p.zips }()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		buffer := &bytes.Buffer{}
+		if err := p.zip(ctx, r, buffer); err != nil {
+			return err
+		}
+
+		response.Header().Set("Content-Type", "application/zip")
+		_, _ = buffer.WriteTo(response)
+
+		return nil
+	}
+}
+
+func (p *Proxy) writeInfo(ctx context.Context, response http.ResponseWriter, r *request, version string) error {
+	info, err := p.info(ctx, r, version)
+	if err != nil {
+		return err
+	}
+
+	response.Header().Set("Content-Type", "application/json")
+
+	return json.NewEncoder(response).Encode(info)
+}
@@ -0,0 +1,565 @@
+package goproxy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"go.eigsys.de/masquerade/pkg/cache"
+	"go.eigsys.de/masquerade/pkg/repository"
+	"golang.org/x/mod/sumdb/dirhash"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+var commitTime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+type mockSource struct {
+	repo       string
+	private    bool
+	privateErr error
+	tags       []string
+	tagsErr    error
+	files      map[string]string
+	archive    map[string]string
+	archiveErr error
+}
+
+func (m *mockSource) Private(_ context.Context, _ string) (bool, error) {
+	return m.private, m.privateErr
+}
+
+func (m *mockSource) Tags(_ context.Context, repo string) ([]string, error) {
+	if repo != m.repo && (m.repo != "" || repo != "repo") {
+		return nil, repository.ErrNotFound
+	}
+
+	return m.tags, m.tagsErr
+}
+
+func (m *mockSource) CommitTime(_ context.Context, _, _ string) (time.Time, error) {
+	return commitTime, nil
+}
+
+func (m *mockSource) File(_ context.Context, _, ref, filePath string) ([]byte, error) {
+	content, ok := m.files[ref+":"+filePath]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+
+	return []byte(content), nil
+}
+
+func (m *mockSource) Archive(_ context.Context, _, _ string) ([]byte, error) {
+	if m.archiveErr != nil {
+		return nil, m.archiveErr
+	}
+
+	buffer := &bytes.Buffer{}
+	writer := zip.NewWriter(buffer)
+	names := make([]string, 0, len(m.archive))
+	for name := range m.archive {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if _, err := writer.Create("owner-repo-abc123/"); err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		file, err := writer.Create("owner-repo-abc123/" + name)
+		if err != nil {
+			return nil, err
+		}
+		_, _ = file.Write([]byte(m.archive[name]))
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func newTestSource() *mockSource {
+	return &mockSource{
+		tags: []string{"v1.0.0", "v1.1.0", "v1.2.0-rc.1", "v2.0.0", "tools/v0.1.0", "invalid", "v1.0"},
+		files: map[string]string{
+			"v1.1.0:go.mod":             "module go.example.com/repo\n",
+			"v2.0.0:v2/go.mod":          "module go.example.com/repo/v2\n",
+			"tools/v0.1.0:tools/go.mod": "module go.example.com/repo/tools\n",
+		},
+		archive: map[string]string{
+			"LICENSE":       "root license\n",
+			"go.mod":        "module go.example.com/repo\n",
+			"repo.go":       "package repo\n",
+			"v2/go.mod":     "module go.example.com/repo/v2\n",
+			"v2/repo.go":    "package repo\n",
+			"tools/LICENSE": "tools license\n",
+			"tools/go.mod":  "module go.example.com/repo/tools\n",
+			"tools/main.go": "package main\n",
+		},
+	}
+}
+
+func newTestProxy(source Source) *Proxy {
+	return New(source, cache.NewMemoizer(cache.NewMemoryStore(0), time.Minute, 0, 0), "go.example.com")
+}
+
+func zipNames(t *testing.T, data []byte) []string {
+	t.Helper()
+
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	for _, file := range reader.File {
+		names = append(names, file.Name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func TestProxy_ServeHTTP(t *testing.T) {
+	tests := []struct {
+		name            string
+		source          Source
+		path            string
+		wantCode        int
+		wantBody        string
+		wantContentType string
+	}{
+		{
+			name:            "list",
+			source:          newTestSource(),
+			path:            "/go.example.com/repo/@v/list",
+			wantCode:        http.StatusOK,
+			wantBody:        "v1.0.0\nv1.1.0\nv1.2.0-rc.1\n",
+			wantContentType: "text/plain; charset=utf-8",
+		},
+		{
+			name:            "list-major-version",
+			source:          newTestSource(),
+			path:            "/go.example.com/repo/v2/@v/list",
+			wantCode:        http.StatusOK,
+			wantBody:        "v2.0.0\n",
+			wantContentType: "text/plain; charset=utf-8",
+		},
+		{
+			name:            "list-nested-module",
+			source:          newTestSource(),
+			path:            "/go.example.com/repo/tools/@v/list",
+			wantCode:        http.StatusOK,
+			wantBody:        "v0.1.0\n",
+			wantContentType: "text/plain; charset=utf-8",
+		},
+		{
+			name:            "latest",
+			source:          newTestSource(),
+			path:            "/go.example.com/repo/@latest",
+			wantCode:        http.StatusOK,
+			wantBody:        `{"Version":"v1.1.0","Time":"2024-01-02T03:04:05Z"}` + "\n",
+			wantContentType: "application/json",
+		},
+		{
+			name:            "latest-prerelease",
+			source:          &mockSource{tags: []string{"v1.0.0-rc.1", "v1.0.0-rc.2"}},
+			path:            "/go.example.com/repo/@latest",
+			wantCode:        http.StatusOK,
+			wantBody:        `{"Version":"v1.0.0-rc.2","Time":"2024-01-02T03:04:05Z"}` + "\n",
+			wantContentType: "application/json",
+		},
+		{
+			name:            "latest-without-versions",
+			source:          &mockSource{},
+			path:            "/go.example.com/repo/@latest",
+			wantCode:        http.StatusNotFound,
+			wantBody:        "not found\n",
+			wantContentType: "text/plain; charset=utf-8",
+		},
+		{
+			name:            "info",
+			source:          newTestSource(),
+			path:            "/go.example.com/repo/@v/v1.0.0.info",
+			wantCode:        http.StatusOK,
+			wantBody:        `{"Version":"v1.0.0","Time":"2024-01-02T03:04:05Z"}` + "\n",
+			wantContentType: "application/json",
+		},
+		{
+			name:            "mod",
+			source:          newTestSource(),
+			path:            "/go.example.com/repo/@v/v1.1.0.mod",
+			wantCode:        http.StatusOK,
+			wantBody:        "module go.example.com/repo\n",
+			wantContentType: "text/plain; charset=utf-8",
+		},
+		{
+			name:            "mod-synthesized",
+			source:          newTestSource(),
+			path:            "/go.example.com/repo/@v/v1.0.0.mod",
+			wantCode:        http.StatusOK,
+			wantBody:        "module go.example.com/repo\n",
+			wantContentType: "text/plain; charset=utf-8",
+		},
+		{
+			name:            "mod-major-subdirectory",
+			source:          newTestSource(),
+			path:            "/go.example.com/repo/v2/@v/v2.0.0.mod",
+			wantCode:        http.StatusOK,
+			wantBody:        "module go.example.com/repo/v2\n",
+			wantContentType: "text/plain; charset=utf-8",
+		},
+		{
+			name:            "unknown-version",
+			source:          newTestSource(),
+			path:            "/go.example.com/repo/@v/v1.3.0.info",
+			wantCode:        http.StatusNotFound,
+			wantBody:        "not found\n",
+			wantContentType: "text/plain; charset=utf-8",
+		},
+		{
+			name:            "unknown-repo",
+			source:          newTestSource(),
+			path:            "/go.example.com/unknown/@v/list",
+			wantCode:        http.StatusNotFound,
+			wantBody:        "not found\n",
+			wantContentType: "text/plain; charset=utf-8",
+		},
+		{
+			name:            "invalid-request",
+			source:          newTestSource(),
+			path:            "/go.example.com/repo/@v/v1.0.0.txt",
+			wantCode:        http.StatusNotFound,
+			wantBody:        "not found\n",
+			wantContentType: "text/plain; charset=utf-8",
+		},
+		{
+			name:            "upstream-error",
+			source:          &mockSource{tagsErr: errors.New("error")},
+			path:            "/go.example.com/repo/@v/list",
+			wantCode:        http.StatusBadGateway,
+			wantBody:        "bad gateway\n",
+			wantContentType: "text/plain; charset=utf-8",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := httptest.NewRecorder()
+
+			newTestProxy(tt.source).ServeHTTP(response, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if response.Code != tt.wantCode {
+				t.Errorf("ServeHTTP() code = %v, want %v", response.Code, tt.wantCode)
+			}
+			if response.Body.String() != tt.wantBody {
+				t.Errorf("ServeHTTP() body = %q, want %q", response.Body.String(), tt.wantBody)
+			}
+			if got := response.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("ServeHTTP() content type = %v, want %v", got, tt.wantContentType)
+			}
+		})
+	}
+}
+
+func TestProxy_ServeHTTP_zip(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		wantNames []string
+	}{
+		{
+			name: "root-module",
+			path: "/go.example.com/repo/@v/v1.1.0.zip",
+			wantNames: []string{
+				"go.example.com/repo@v1.1.0/LICENSE",
+				"go.example.com/repo@v1.1.0/go.mod",
+				"go.example.com/repo@v1.1.0/repo.go",
+			},
+		},
+		{
+			name: "major-subdirectory",
+			path: "/go.example.com/repo/v2/@v/v2.0.0.zip",
+			wantNames: []string{
+				"go.example.com/repo/v2@v2.0.0/LICENSE",
+				"go.example.com/repo/v2@v2.0.0/go.mod",
+				"go.example.com/repo/v2@v2.0.0/repo.go",
+			},
+		},
+		{
+			name: "nested-module",
+			path: "/go.example.com/repo/tools/@v/v0.1.0.zip",
+			wantNames: []string{
+				"go.example.com/repo/tools@v0.1.0/LICENSE",
+				"go.example.com/repo/tools@v0.1.0/go.mod",
+				"go.example.com/repo/tools@v0.1.0/main.go",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := httptest.NewRecorder()
+
+			newTestProxy(newTestSource()).ServeHTTP(response, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if response.Code != http.StatusOK {
+				t.Fatalf("ServeHTTP() code = %v, body = %s", response.Code, response.Body.String())
+			}
+			if got := response.Header().Get("Content-Type"); got != "application/zip" {
+				t.Errorf("ServeHTTP() content type = %v", got)
+			}
+			if got := zipNames(t, response.Body.Bytes()); !reflect.DeepEqual(got, tt.wantNames) {
+				t.Errorf("ServeHTTP() files = %v, want %v", got, tt.wantNames)
+			}
+		})
+	}
+}
+
+// TestProxy_ServeHTTP_zipChecksum compares the checksum with the one of the zip that the go command builds from a git
+// repository with the same files, which adds the LICENSE of the repository root to the module in a subdirectory.
+func TestProxy_ServeHTTP_zipChecksum(t *testing.T) {
+	source := &mockSource{
+		repo:  "repo.git",
+		tags:  []string{"sub/v1.0.0"},
+		files: map[string]string{"sub/v1.0.0:sub/go.mod": "module example.com/repo.git/sub\n\ngo 1.21\n"},
+		archive: map[string]string{
+			"LICENSE":    "The license\n",
+			"README":     "root\n",
+			"sub/go.mod": "module example.com/repo.git/sub\n\ngo 1.21\n",
+			"sub/sub.go": "package sub\n",
+		},
+	}
+	response := httptest.NewRecorder()
+
+	New(source, cache.NewMemoizer(cache.NewMemoryStore(0), time.Minute, 0, 0), "example.com").ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/example.com/repo.git/sub/@v/v1.0.0.zip", nil))
+
+	if response.Code != http.StatusOK {
+		t.Fatalf("ServeHTTP() code = %v, body = %s", response.Code, response.Body.String())
+	}
+
+	zipFile := filepath.Join(t.TempDir(), "v1.0.0.zip")
+	if err := os.WriteFile(zipFile, response.Body.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := dirhash.HashZip(zipFile, dirhash.Hash1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "h1:XoBSWgASfa9vsC0+b4Ccq0IlPCtqYoHV+CKIQv/Fzrk="; got != want {
+		t.Errorf("checksum = %v, want %v", got, want)
+	}
+}
+
+func TestProxy_ServeHTTP_private(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   *mockSource
+		wantCode int
+	}{
+		{name: "public", source: newTestSource(), wantCode: http.StatusOK},
+		{name: "private", source: &mockSource{private: true}, wantCode: http.StatusNotFound},
+		{name: "error", source: &mockSource{privateErr: repository.ErrUnavailable}, wantCode: http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := httptest.NewRecorder()
+
+			newTestProxy(tt.source).ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/go.example.com/repo/@v/v1.1.0.mod", nil))
+
+			if response.Code != tt.wantCode {
+				t.Errorf("ServeHTTP() code = %v, want %v", response.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestProxy_ServeHTTP_zipConcurrency(t *testing.T) {
+	proxy := newTestProxy(newTestSource())
+	for range maxConcurrentZips {
+		proxy.zips <- struct{}{}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	response := httptest.NewRecorder()
+
+	proxy.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/go.example.com/repo/@v/v1.1.0.zip", nil).WithContext(ctx))
+
+	if response.Code != http.StatusBadGateway {
+		t.Errorf("ServeHTTP() code = %v, want a timeout while all zips are built", response.Code)
+	}
+}
+
+func TestProxy_ServeHTTP_zipError(t *testing.T) {
+	source := newTestSource()
+	source.archiveErr = errors.New("error")
+	response := httptest.NewRecorder()
+
+	newTestProxy(source).ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/go.example.com/repo/@v/v1.1.0.zip", nil))
+
+	if response.Code != http.StatusBadGateway {
+		t.Errorf("ServeHTTP() code = %v", response.Code)
+	}
+}
+
+func TestProxy_ServeHTTP_errorHandler(t *testing.T) {
+	var handledErr error
+	proxy := New(&mockSource{tagsErr: repository.ErrRateLimited}, cache.NewMemoizer(cache.NewMemoryStore(0), time.Minute, 0, 0), "go.example.com", WithErrorHandler(func(response http.ResponseWriter, _ *http.Request, err error) {
+		handledErr = err
+		response.WriteHeader(http.StatusTooManyRequests)
+	}))
+	response := httptest.NewRecorder()
+
+	proxy.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/go.example.com/repo/@v/list", nil))
+
+	if response.Code != http.StatusTooManyRequests || !errors.Is(handledErr, repository.ErrRateLimited) {
+		t.Errorf("ServeHTTP() code = %v, error = %v", response.Code, handledErr)
+	}
+}
+
+func TestProxy_parseRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		want    *request
+		wantErr bool
+	}{
+		{
+			name: "list",
+			path: "/go.example.com/repo/@v/list",
+			want: &request{modulePath: "go.example.com/repo", repo: "repo", action: "list"},
+		},
+		{
+			name: "latest",
+			path: "/go.example.com/repo/tools/v2/@latest",
+			want: &request{modulePath: "go.example.com/repo/tools/v2", repo: "repo", dir: "tools", pathMajor: "/v2", action: "latest"},
+		},
+		{
+			name: "escaped",
+			path: "/go.example.com/!repo/@v/v1.0.0-!r!c.info",
+			want: &request{modulePath: "go.example.com/Repo", repo: "Repo", action: "info", version: "v1.0.0-RC"},
+		},
+		{
+			name:    "other-host",
+			path:    "/github.com/owner/repo/@v/list",
+			wantErr: true,
+		},
+		{
+			name:    "host-only",
+			path:    "/go.example.com/@v/list",
+			wantErr: true,
+		},
+		{
+			name:    "invalid-escaping",
+			path:    "/go.example.com/Repo/@v/list",
+			wantErr: true,
+		},
+		{
+			name:    "invalid-major-version",
+			path:    "/go.example.com/repo/v1/@v/list",
+			wantErr: true,
+		},
+		{
+			name:    "non-canonical-version",
+			path:    "/go.example.com/repo/@v/v1.0.info",
+			wantErr: true,
+		},
+		{
+			name:    "version-major-mismatch",
+			path:    "/go.example.com/repo/@v/v2.0.0.info",
+			wantErr: true,
+		},
+		{
+			name:    "invalid-version-escaping",
+			path:    "/go.example.com/repo/@v/v1.0.0-RC.info",
+			wantErr: true,
+		},
+		{
+			name:    "unknown-file",
+			path:    "/go.example.com/repo/@v/v1.0.0.txt",
+			wantErr: true,
+		},
+		{
+			name:    "no-proxy-request",
+			path:    "/go.example.com/repo",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newTestProxy(nil).parseRequest(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseRequest() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseRequest() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsProxyRequest(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "/go.example.com/repo/@v/list", want: true},
+		{path: "/go.example.com/repo/@latest", want: true},
+		{path: "/repo", want: false},
+		{path: "/repo/sub/pkg", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := IsProxyRequest(tt.path); got != tt.want {
+				t.Errorf("IsProxyRequest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_latestVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions []string
+		want     string
+	}{
+		{name: "release", versions: []string{"v1.0.0", "v1.1.0", "v1.2.0-rc.1"}, want: "v1.1.0"},
+		{name: "prerelease", versions: []string{"v1.0.0-rc.1", "v1.0.0-rc.2"}, want: "v1.0.0-rc.2"},
+		{name: "empty", versions: []string{}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := latestVersion(tt.versions); got != tt.want {
+				t.Errorf("latestVersion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	source := &mockSource{}
+	cache := cache.NewMemoizer(cache.NewMemoryStore(0), time.Minute, 0, 0)
+	want := &Proxy{source: source, cache: cache, packageHost: "go.example.com"}
+
+	got := New(source, cache, "go.example.com")
+	if cap(got.zips) != maxConcurrentZips {
+		t.Errorf("New() allows %d concurrent zips", cap(got.zips))
+	}
+
+	got.zips = nil
+	if !reflect.DeepEqual(got, want) {
+		t.Error("unexpected result")
+	}
+}
@@ -0,0 +1,71 @@
+package importpath
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+const maxLength = 1024
+
+var elementRegexp = regexp.MustCompile(`^[a-zA-Z0-9-_.~]+$`)
+
+var majorVersionRegexp = regexp.MustCompile(`^v([2-9]|[1-9][0-9]+)$`)
+
+var ErrInvalid = errors.New("invalid import path")
+
+type ImportPath struct {
+	Repo    string
+	Major   string
+	Subpath string
+}
+
+func (i *ImportPath) String() string {
+	elements := []string{i.Repo}
+
+	for _, element := range []string{i.Major, i.Subpath} {
+		if element != "" {
+			elements = append(elements, element)
+		}
+	}
+
+	return strings.Join(elements, "/")
+}
+
+func IsMajorVersion(element string) bool {
+	return majorVersionRegexp.MatchString(element)
+}
+
+func isValidElement(element string) bool {
+	return elementRegexp.MatchString(element) && !strings.HasPrefix(element, ".") && !strings.HasSuffix(element, ".")
+}
+
+func Parse(urlPath string) (*ImportPath, error) {
+	trimmed := strings.TrimSuffix(strings.TrimPrefix(urlPath, "/"), "/")
+	if len(trimmed) > maxLength {
+		return nil, ErrInvalid
+	}
+
+	if trimmed == "" {
+		return &ImportPath{}, nil
+	}
+
+	elements := strings.Split(trimmed, "/")
+	for _, element := range elements {
+		if !isValidElement(element) {
+			return nil, ErrInvalid
+		}
+	}
+
+	importPath := &ImportPath{Repo: elements[0]}
+	elements = elements[1:]
+
+	if len(elements) > 0 && IsMajorVersion(elements[0]) {
+		importPath.Major = elements[0]
+		elements = elements[1:]
+	}
+
+	importPath.Subpath = strings.Join(elements, "/")
+
+	return importPath, nil
+}
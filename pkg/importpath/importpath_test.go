@@ -0,0 +1,218 @@
+package importpath
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		urlPath string
+		want    *ImportPath
+		wantErr bool
+	}{
+		{
+			name:    "empty",
+			urlPath: "",
+			want:    &ImportPath{},
+		},
+		{
+			name:    "slash",
+			urlPath: "/",
+			want:    &ImportPath{},
+		},
+		{
+			name:    "repo",
+			urlPath: "/repo",
+			want:    &ImportPath{Repo: "repo"},
+		},
+		{
+			name:    "repo-trailing-slash",
+			urlPath: "/repo/",
+			want:    &ImportPath{Repo: "repo"},
+		},
+		{
+			name:    "sub-package",
+			urlPath: "/repo/sub/pkg",
+			want:    &ImportPath{Repo: "repo", Subpath: "sub/pkg"},
+		},
+		{
+			name:    "special-characters",
+			urlPath: "/the-repo/the_sub.pkg/~v1",
+			want:    &ImportPath{Repo: "the-repo", Subpath: "the_sub.pkg/~v1"},
+		},
+		{
+			name:    "major-version",
+			urlPath: "/repo/v2",
+			want:    &ImportPath{Repo: "repo", Major: "v2"},
+		},
+		{
+			name:    "major-version-sub-package",
+			urlPath: "/repo/v12/sub/pkg",
+			want:    &ImportPath{Repo: "repo", Major: "v12", Subpath: "sub/pkg"},
+		},
+		{
+			name:    "major-version-1",
+			urlPath: "/repo/v1/pkg",
+			want:    &ImportPath{Repo: "repo", Subpath: "v1/pkg"},
+		},
+		{
+			name:    "major-version-not-first",
+			urlPath: "/repo/sub/v2",
+			want:    &ImportPath{Repo: "repo", Subpath: "sub/v2"},
+		},
+		{
+			name:    "empty-element",
+			urlPath: "/repo//pkg",
+			wantErr: true,
+		},
+		{
+			name:    "dot-dot",
+			urlPath: "/repo/../pkg",
+			wantErr: true,
+		},
+		{
+			name:    "leading-dot",
+			urlPath: "/repo/.git",
+			wantErr: true,
+		},
+		{
+			name:    "trailing-dot",
+			urlPath: "/repo/pkg.",
+			wantErr: true,
+		},
+		{
+			name:    "invalid-character",
+			urlPath: "/repo/p%kg",
+			wantErr: true,
+		},
+		{
+			name:    "too-long",
+			urlPath: "/repo/" + strings.Repeat("a", maxLength),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.urlPath)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil && !errors.Is(err, ErrInvalid) {
+				t.Errorf("Parse() error = %v, want %v", err, ErrInvalid)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportPath_String(t *testing.T) {
+	tests := []struct {
+		name       string
+		importPath *ImportPath
+		want       string
+	}{
+		{
+			name:       "repo",
+			importPath: &ImportPath{Repo: "repo"},
+			want:       "repo",
+		},
+		{
+			name:       "sub-package",
+			importPath: &ImportPath{Repo: "repo", Subpath: "sub/pkg"},
+			want:       "repo/sub/pkg",
+		},
+		{
+			name:       "major-version",
+			importPath: &ImportPath{Repo: "repo", Major: "v2"},
+			want:       "repo/v2",
+		},
+		{
+			name:       "major-version-sub-package",
+			importPath: &ImportPath{Repo: "repo", Major: "v2", Subpath: "sub/pkg"},
+			want:       "repo/v2/sub/pkg",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.importPath.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsMajorVersion(t *testing.T) {
+	tests := []struct {
+		element string
+		want    bool
+	}{
+		{element: "v2", want: true},
+		{element: "v10", want: true},
+		{element: "v0", want: false},
+		{element: "v1", want: false},
+		{element: "v02", want: false},
+		{element: "v2.1", want: false},
+		{element: "V2", want: false},
+		{element: "pkg", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.element, func(t *testing.T) {
+			if got := IsMajorVersion(tt.element); got != tt.want {
+				t.Errorf("IsMajorVersion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for _, seed := range []string{"", "/", "/repo", "/repo/", "/repo/sub/pkg", "/repo/v2/pkg", "/repo//pkg", "/repo/../pkg", "/.repo", "/repo/p%kg"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, urlPath string) {
+		got, err := Parse(urlPath)
+		if err != nil {
+			if got != nil {
+				t.Errorf("Parse(%q) returned a result and an error", urlPath)
+			}
+			return
+		}
+
+		if got.Repo == "" {
+			if got.Major != "" || got.Subpath != "" {
+				t.Errorf("Parse(%q) returned a major version or subpath without repo", urlPath)
+			}
+			return
+		}
+
+		if got.Major != "" && !IsMajorVersion(got.Major) {
+			t.Errorf("Parse(%q) returned invalid major version %q", urlPath, got.Major)
+		}
+
+		if strings.Contains(got.Repo, "/") {
+			t.Errorf("Parse(%q) returned repo %q containing a slash", urlPath, got.Repo)
+		}
+
+		for _, element := range strings.Split(got.String(), "/") {
+			if !isValidElement(element) {
+				t.Errorf("Parse(%q) returned invalid element %q", urlPath, element)
+			}
+		}
+
+		if want := strings.TrimSuffix(strings.TrimPrefix(urlPath, "/"), "/"); got.String() != want {
+			t.Errorf("Parse(%q) = %q, want %q", urlPath, got.String(), want)
+		}
+
+		reparsed, err := Parse("/" + got.String())
+		if err != nil || !reflect.DeepEqual(reparsed, got) {
+			t.Errorf("Parse(%q) is not stable: %v, %v", urlPath, reparsed, err)
+		}
+	})
+}
@@ -0,0 +1,81 @@
+// Package apiclient contains the HTTP plumbing shared by the clients of the JSON REST APIs of GitLab and Gitea.
+package apiclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type ErrorResponse struct {
+	Response *http.Response
+}
+
+func (e *ErrorResponse) Error() string {
+	return fmt.Sprintf("unexpected status code %d", e.Response.StatusCode)
+}
+
+// Client sends authenticated GET requests to an API and decodes the JSON responses.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	authHeader string
+	authValue  string
+}
+
+// New returns a Client for the API at baseURL, which authenticates by setting authHeader to authValue, unless
+// authValue is empty.
+func New(baseURL string, httpClient *http.Client, authHeader, authValue string) (*Client, error) {
+	parsedBaseURL, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if parsedBaseURL.Scheme == "" || parsedBaseURL.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q", baseURL)
+	}
+
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &Client{
+		baseURL:    strings.TrimSuffix(parsedBaseURL.String(), "/"),
+		httpClient: httpClient,
+		authHeader: authHeader,
+		authValue:  authValue,
+	}, nil
+}
+
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// Get decodes the response to a GET request of endpoint into v. Responses without a 2xx status code are returned
+// as ErrorResponse.
+func (c *Client) Get(ctx context.Context, endpoint string, v any) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if c.authValue != "" {
+		req.Header.Set(c.authHeader, c.authValue)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return resp, &ErrorResponse{Response: resp}
+	}
+
+	return resp, json.NewDecoder(resp.Body).Decode(v)
+}
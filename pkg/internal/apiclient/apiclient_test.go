@@ -0,0 +1,151 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"go.eigsys.de/masquerade/pkg/internal/apiclient/apiclienttest"
+	"net/http"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name        string
+		baseURL     string
+		wantBaseURL string
+		wantErr     bool
+	}{
+		{
+			name:        "ok",
+			baseURL:     "https://git.example.com",
+			wantBaseURL: "https://git.example.com",
+		},
+		{
+			name:        "trailing-slash",
+			baseURL:     "https://git.example.com/",
+			wantBaseURL: "https://git.example.com",
+		},
+		{
+			name:        "path-prefix",
+			baseURL:     "https://example.com/git",
+			wantBaseURL: "https://example.com/git",
+		},
+		{
+			name:    "missing-base-url",
+			baseURL: "",
+			wantErr: true,
+		},
+		{
+			name:    "missing-scheme",
+			baseURL: "git.example.com",
+			wantErr: true,
+		},
+		{
+			name:    "invalid-url",
+			baseURL: "https://git.example.com/%zz",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(tt.baseURL, nil, "Authorization", "")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && got.BaseURL() != tt.wantBaseURL {
+				t.Errorf("New() baseURL = %v, want %v", got.BaseURL(), tt.wantBaseURL)
+			}
+		})
+	}
+}
+
+func TestClient_Get(t *testing.T) {
+	server := apiclienttest.NewServer(t, "/api/", "Authorization", map[string]string{
+		"/api/the-object":   `{"name":"the-object"}`,
+		"/api/invalid-json": `{`,
+	})
+	tests := []struct {
+		name           string
+		token          string
+		endpoint       string
+		want           string
+		wantStatusCode int
+		wantErr        bool
+	}{
+		{
+			name:           "ok",
+			endpoint:       "/api/the-object",
+			want:           "the-object",
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "ok-with-token",
+			token:          "the-token",
+			endpoint:       "/api/the-object",
+			want:           "the-object",
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "not-found",
+			endpoint:       "/api/unknown",
+			wantStatusCode: http.StatusNotFound,
+			wantErr:        true,
+		},
+		{
+			name:           "unauthorized",
+			token:          apiclienttest.InvalidToken,
+			endpoint:       "/api/the-object",
+			wantStatusCode: http.StatusUnauthorized,
+			wantErr:        true,
+		},
+		{
+			name:           "invalid-json",
+			endpoint:       "/api/invalid-json",
+			wantStatusCode: http.StatusOK,
+			wantErr:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(server.URL, server.Client(), "Authorization", tt.token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := struct {
+				Name string `json:"name"`
+			}{}
+			resp, err := c.Get(context.Background(), tt.endpoint, &got)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Get() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if resp == nil || resp.StatusCode != tt.wantStatusCode {
+				t.Errorf("Get() resp = %v, wantStatusCode %v", resp, tt.wantStatusCode)
+			}
+			if got.Name != tt.want {
+				t.Errorf("Get() got = %v, want %v", got.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_Get_error(t *testing.T) {
+	c, err := New("http://127.0.0.1:0", nil, "Authorization", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Get(context.Background(), "/api/the-object", &struct{}{}); err == nil {
+		t.Error("no error")
+	}
+}
+
+func TestErrorResponse_Error(t *testing.T) {
+	err := error(&ErrorResponse{Response: &http.Response{StatusCode: http.StatusTeapot}})
+
+	var errorResponse *ErrorResponse
+	if !errors.As(err, &errorResponse) || err.Error() != "unexpected status code 418" {
+		t.Errorf("wrong error: %v", err)
+	}
+}
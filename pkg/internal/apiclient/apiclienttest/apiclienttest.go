@@ -0,0 +1,41 @@
+// Package apiclienttest provides a fake API server for the tests of the apiclient based clients.
+package apiclienttest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// InvalidToken is rejected by the server with 401 Unauthorized.
+const InvalidToken = "invalid-token"
+
+// NewServer starts a server answering GET requests below prefix with the JSON bodies of responses, which are keyed
+// by their escaped path. Paths are matched case-insensitively, unknown paths are answered with 404 Not Found and
+// requests whose authHeader ends with InvalidToken with 401 Unauthorized.
+func NewServer(t *testing.T, prefix, authHeader string, responses map[string]string) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(prefix, func(response http.ResponseWriter, request *http.Request) {
+		if strings.HasSuffix(request.Header.Get(authHeader), InvalidToken) {
+			http.Error(response, `{"message":"401 Unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+
+		for path, body := range responses {
+			if strings.EqualFold(request.URL.EscapedPath(), path) {
+				_, _ = response.Write([]byte(body))
+				return
+			}
+		}
+
+		http.Error(response, `{"message":"404 Not Found"}`, http.StatusNotFound)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
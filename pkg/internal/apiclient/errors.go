@@ -0,0 +1,47 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.eigsys.de/masquerade/pkg/repository"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ClassifyError maps err of a request to a VCS API, which got resp (or nil), to the error classes of the repository
+// package.
+func ClassifyError(resp *http.Response, err error) error {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return err
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("%w: %w", repository.ErrTimeout, err)
+	case resp == nil:
+		// The request didn't get a response at all, e.g. because of a DNS or connection failure.
+		return fmt.Errorf("%w: %w", repository.ErrUnavailable, err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return repository.ErrNotFound
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("%w: %w", repository.ErrForbidden, err)
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return withRetryAfter(resp, fmt.Errorf("%w: %w", repository.ErrRateLimited, err))
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return withRetryAfter(resp, fmt.Errorf("%w: %w", repository.ErrUnavailable, err))
+	default:
+		return err
+	}
+}
+
+func withRetryAfter(resp *http.Response, err error) error {
+	seconds, parseErr := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if parseErr != nil || seconds <= 0 {
+		return err
+	}
+
+	return &repository.RetryAfterError{Err: err, RetryAfter: time.Duration(seconds) * time.Second}
+}
@@ -0,0 +1,91 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"go.eigsys.de/masquerade/pkg/repository"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClassifyError(t *testing.T) {
+	genericError := errors.New("generic error")
+	tests := []struct {
+		name           string
+		resp           *http.Response
+		err            error
+		wantErrResult  error
+		wantRetryAfter time.Duration
+	}{
+		{
+			name:          "not-found",
+			resp:          &http.Response{StatusCode: http.StatusNotFound},
+			err:           genericError,
+			wantErrResult: repository.ErrNotFound,
+		},
+		{
+			name:          "unauthorized",
+			resp:          &http.Response{StatusCode: http.StatusUnauthorized},
+			err:           genericError,
+			wantErrResult: repository.ErrForbidden,
+		},
+		{
+			name:          "forbidden",
+			resp:          &http.Response{StatusCode: http.StatusForbidden},
+			err:           genericError,
+			wantErrResult: repository.ErrForbidden,
+		},
+		{
+			name:           "too-many-requests",
+			resp:           &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"30"}}},
+			err:            genericError,
+			wantErrResult:  repository.ErrRateLimited,
+			wantRetryAfter: 30 * time.Second,
+		},
+		{
+			name:          "service-unavailable",
+			resp:          &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}},
+			err:           genericError,
+			wantErrResult: repository.ErrUnavailable,
+		},
+		{
+			name:          "no-response",
+			err:           genericError,
+			wantErrResult: repository.ErrUnavailable,
+		},
+		{
+			name:          "timeout",
+			err:           context.DeadlineExceeded,
+			wantErrResult: repository.ErrTimeout,
+		},
+		{
+			name:          "canceled",
+			err:           context.Canceled,
+			wantErrResult: context.Canceled,
+		},
+		{
+			name:          "unprocessable-entity",
+			resp:          &http.Response{StatusCode: http.StatusUnprocessableEntity},
+			err:           genericError,
+			wantErrResult: genericError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ClassifyError(tt.resp, tt.err)
+			if !errors.Is(err, tt.wantErrResult) {
+				t.Errorf("ClassifyError() error = %v, wantErrResult %v", err, tt.wantErrResult)
+			}
+
+			var retryAfterErr *repository.RetryAfterError
+			var gotRetryAfter time.Duration
+			if errors.As(err, &retryAfterErr) {
+				gotRetryAfter = retryAfterErr.RetryAfter
+			}
+			if gotRetryAfter != tt.wantRetryAfter {
+				t.Errorf("ClassifyError() retry after = %v, want %v", gotRetryAfter, tt.wantRetryAfter)
+			}
+		})
+	}
+}
@@ -1,10 +1,65 @@
 package repository
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 type Repository interface {
 	GetRepoRoot() string
 	GetProjectWebsiteOrFallback(fallback string) string
+	GetSourceURLs(dir string) SourceURLs
 }
 
-var ErrNotFound = errors.New("not found")
+type SourceURLs struct {
+	Home      string
+	Directory string
+	File      string
+}
+
+func NewSourceURLs(home, branch, dir, directoryPath, filePath string) SourceURLs {
+	if home == "" || branch == "" {
+		return SourceURLs{}
+	}
+
+	suffix := "/" + branch
+	if dir != "" {
+		suffix += "/" + dir
+	}
+
+	return SourceURLs{
+		Home:      home,
+		Directory: home + "/" + directoryPath + suffix + "{/dir}",
+		File:      home + "/" + filePath + suffix + "{/dir}/{file}#L{line}",
+	}
+}
+
+type MajorVersionLayout string
+
+const (
+	MajorVersionSubdirectory MajorVersionLayout = "subdirectory"
+	MajorVersionBranch       MajorVersionLayout = "branch"
+)
+
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrInvalidName = errors.New("invalid repository name")
+	ErrForbidden   = errors.New("forbidden")
+	ErrRateLimited = errors.New("rate limited")
+	ErrUnavailable = errors.New("upstream unavailable")
+	ErrTimeout     = errors.New("upstream timeout")
+)
+
+// RetryAfterError carries the duration after which a failed request may be retried.
+type RetryAfterError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
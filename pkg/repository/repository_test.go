@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewSourceURLs(t *testing.T) {
+	type args struct {
+		home          string
+		branch        string
+		dir           string
+		directoryPath string
+		filePath      string
+	}
+	tests := []struct {
+		name string
+		args args
+		want SourceURLs
+	}{
+		{
+			name: "root",
+			args: args{home: "https://example.com/repo", branch: "main", directoryPath: "tree", filePath: "blob"},
+			want: SourceURLs{
+				Home:      "https://example.com/repo",
+				Directory: "https://example.com/repo/tree/main{/dir}",
+				File:      "https://example.com/repo/blob/main{/dir}/{file}#L{line}",
+			},
+		},
+		{
+			name: "dir",
+			args: args{home: "https://example.com/repo", branch: "main", dir: "tools", directoryPath: "tree", filePath: "blob"},
+			want: SourceURLs{
+				Home:      "https://example.com/repo",
+				Directory: "https://example.com/repo/tree/main/tools{/dir}",
+				File:      "https://example.com/repo/blob/main/tools{/dir}/{file}#L{line}",
+			},
+		},
+		{
+			name: "home-missing",
+			args: args{branch: "main", directoryPath: "tree", filePath: "blob"},
+			want: SourceURLs{},
+		},
+		{
+			name: "branch-missing",
+			args: args{home: "https://example.com/repo", directoryPath: "tree", filePath: "blob"},
+			want: SourceURLs{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewSourceURLs(tt.args.home, tt.args.branch, tt.args.dir, tt.args.directoryPath, tt.args.filePath)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewSourceURLs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
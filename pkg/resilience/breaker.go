@@ -0,0 +1,135 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.eigsys.de/masquerade/pkg/repository"
+	"log"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = fmt.Errorf("%w: circuit breaker open", repository.ErrUnavailable)
+
+type State int
+
+const (
+	StateClosed State = iota
+	StateHalfOpen
+	StateOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half-open"
+	case StateOpen:
+		return "open"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+// CircuitBreaker opens after threshold consecutive transient failures, and then refuses calls until the cooldown
+// has passed. Afterward, a single probe call decides whether it closes or opens again.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: max(threshold, 1),
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+func (b *CircuitBreaker) Do(fn func() error) error {
+	if b == nil {
+		return fn()
+	}
+
+	if err := b.allow(); err != nil {
+		return err
+	}
+
+	err := fn()
+	b.record(err)
+
+	return err
+}
+
+func (b *CircuitBreaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateOpen {
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+
+		b.setState(StateHalfOpen)
+	}
+
+	if b.state == StateHalfOpen {
+		if b.probing {
+			return ErrCircuitOpen
+		}
+
+		b.probing = true
+	}
+
+	return nil
+}
+
+func (b *CircuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+
+	if errors.Is(err, context.Canceled) {
+		// The caller gave up, which says nothing about the backend.
+		return
+	}
+
+	if !IsTransient(err) {
+		b.failures = 0
+		b.setState(StateClosed)
+		return
+	}
+
+	b.failures++
+
+	if b.state == StateHalfOpen || b.failures >= b.threshold {
+		b.openedAt = b.now()
+		b.setState(StateOpen)
+	}
+}
+
+func (b *CircuitBreaker) setState(state State) {
+	if b.state == state {
+		return
+	}
+
+	log.Printf("circuit breaker: %s -> %s", b.state, state)
+	b.state = state
+}
@@ -0,0 +1,78 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"go.eigsys.de/masquerade/pkg/repository"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_Do(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	b := NewCircuitBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+	fail := func() error { return repository.ErrUnavailable }
+	succeed := func() error { return nil }
+
+	steps := []struct {
+		name      string
+		advance   time.Duration
+		fn        func() error
+		wantErr   error
+		wantState State
+	}{
+		{name: "first-failure", fn: fail, wantErr: repository.ErrUnavailable, wantState: StateClosed},
+		{name: "second-failure", fn: fail, wantErr: repository.ErrUnavailable, wantState: StateOpen},
+		{name: "short-circuit", fn: succeed, wantErr: ErrCircuitOpen, wantState: StateOpen},
+		{name: "failed-probe", advance: time.Minute, fn: fail, wantErr: repository.ErrUnavailable, wantState: StateOpen},
+		{name: "short-circuit-again", advance: time.Second, fn: succeed, wantErr: ErrCircuitOpen, wantState: StateOpen},
+		{name: "canceled-probe", advance: time.Minute, fn: func() error { return context.Canceled }, wantErr: context.Canceled, wantState: StateHalfOpen},
+		{name: "successful-probe", fn: succeed, wantState: StateClosed},
+		{name: "permanent-error", fn: func() error { return repository.ErrNotFound }, wantErr: repository.ErrNotFound, wantState: StateClosed},
+	}
+	for _, step := range steps {
+		now = now.Add(step.advance)
+
+		err := b.Do(step.fn)
+		if !errors.Is(err, step.wantErr) || (step.wantErr == nil && err != nil) {
+			t.Errorf("%s: Do() error = %v, want %v", step.name, err, step.wantErr)
+		}
+		if state := b.State(); state != step.wantState {
+			t.Errorf("%s: State() = %v, want %v", step.name, state, step.wantState)
+		}
+	}
+}
+
+func TestCircuitBreaker_Do_singleProbe(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	b := NewCircuitBreaker(1, time.Minute)
+	b.now = func() time.Time { return now }
+
+	_ = b.Do(func() error { return repository.ErrUnavailable })
+	now = now.Add(time.Minute)
+
+	_ = b.Do(func() error {
+		if err := b.Do(func() error { return nil }); !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("Do() error = %v during probe, want %v", err, ErrCircuitOpen)
+		}
+
+		return nil
+	})
+}
+
+func TestCircuitBreaker_Do_nil(t *testing.T) {
+	var b *CircuitBreaker
+
+	if err := b.Do(func() error { return nil }); err != nil {
+		t.Errorf("Do() error = %v", err)
+	}
+}
+
+func TestState_String(t *testing.T) {
+	for state, want := range map[State]string{StateClosed: "closed", StateHalfOpen: "half-open", StateOpen: "open", State(7): "State(7)"} {
+		if got := state.String(); got != want {
+			t.Errorf("String() = %v, want %v", got, want)
+		}
+	}
+}
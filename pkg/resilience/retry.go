@@ -0,0 +1,91 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"go.eigsys.de/masquerade/pkg/repository"
+	"math/rand/v2"
+	"net"
+	"time"
+)
+
+// IsTransient reports whether err is worth retrying, i.e. whether it's known to indicate a failing backend rather
+// than a definitive answer. Unclassified errors aren't retried.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+
+	if errors.Is(err, repository.ErrUnavailable) || errors.Is(err, repository.ErrTimeout) {
+		return true
+	}
+
+	var netErr net.Error
+
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+type Retrier struct {
+	attempts  int
+	baseDelay time.Duration
+	maxDelay  time.Duration
+	jitter    func(n int64) int64
+}
+
+func NewRetrier(attempts int, baseDelay, maxDelay time.Duration) *Retrier {
+	return &Retrier{
+		attempts:  max(attempts, 1),
+		baseDelay: baseDelay,
+		maxDelay:  maxDelay,
+		jitter:    rand.Int64N,
+	}
+}
+
+// Do calls fn until it succeeds, fails permanently, or the attempts are used up.
+// It gives up early if the next attempt wouldn't start before the deadline of ctx.
+func (r *Retrier) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if r == nil {
+		return fn(ctx)
+	}
+
+	var err error
+
+	for attempt := 0; attempt < r.attempts; attempt++ {
+		if attempt > 0 {
+			delay := r.delay(attempt)
+
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+				return err
+			}
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+		}
+
+		if err = fn(ctx); !IsTransient(err) {
+			return err
+		}
+	}
+
+	return err
+}
+
+// delay returns a random duration up to the exponentially growing backoff ("full jitter").
+func (r *Retrier) delay(attempt int) time.Duration {
+	backoff := r.baseDelay
+	for i := 1; i < attempt && backoff < r.maxDelay; i++ {
+		backoff *= 2
+	}
+
+	backoff = min(backoff, r.maxDelay)
+	if backoff <= 0 {
+		return 0
+	}
+
+	return time.Duration(r.jitter(int64(backoff)) + 1)
+}
@@ -0,0 +1,145 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.eigsys.de/masquerade/pkg/repository"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "not-found", err: repository.ErrNotFound, want: false},
+		{name: "rate-limited", err: fmt.Errorf("%w: error", repository.ErrRateLimited), want: false},
+		{name: "canceled", err: context.Canceled, want: false},
+		{name: "circuit-open", err: ErrCircuitOpen, want: false},
+		{name: "unavailable", err: fmt.Errorf("%w: error", repository.ErrUnavailable), want: true},
+		{name: "timeout", err: repository.ErrTimeout, want: true},
+		{name: "forbidden", err: fmt.Errorf("%w: error", repository.ErrForbidden), want: false},
+		{name: "net-timeout", err: &net.DNSError{Err: "timeout", IsTimeout: true}, want: true},
+		{name: "net-error", err: &net.DNSError{Err: "no such host", IsNotFound: true}, want: false},
+		{name: "unclassified", err: errors.New("error"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransient(tt.err); got != tt.want {
+				t.Errorf("IsTransient() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetrier_Do(t *testing.T) {
+	tests := []struct {
+		name      string
+		errs      []error
+		attempts  int
+		wantCalls int
+		wantErr   error
+	}{
+		{
+			name:      "success",
+			errs:      []error{nil},
+			attempts:  3,
+			wantCalls: 1,
+		},
+		{
+			name:      "transient-then-success",
+			errs:      []error{repository.ErrUnavailable, repository.ErrTimeout, nil},
+			attempts:  3,
+			wantCalls: 3,
+		},
+		{
+			name:      "exhausted",
+			errs:      []error{repository.ErrUnavailable, repository.ErrUnavailable, repository.ErrTimeout},
+			attempts:  3,
+			wantCalls: 3,
+			wantErr:   repository.ErrTimeout,
+		},
+		{
+			name:      "permanent",
+			errs:      []error{repository.ErrNotFound},
+			attempts:  3,
+			wantCalls: 1,
+			wantErr:   repository.ErrNotFound,
+		},
+		{
+			name:      "single-attempt",
+			errs:      []error{repository.ErrUnavailable},
+			attempts:  0,
+			wantCalls: 1,
+			wantErr:   repository.ErrUnavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRetrier(tt.attempts, time.Millisecond, 4*time.Millisecond)
+			calls := 0
+
+			err := r.Do(context.Background(), func(_ context.Context) error {
+				calls++
+				return tt.errs[calls-1]
+			})
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("Do() error = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("Do() calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetrier_Do_deadline(t *testing.T) {
+	r := NewRetrier(5, time.Hour, time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	calls := 0
+
+	err := r.Do(ctx, func(_ context.Context) error {
+		calls++
+		return repository.ErrUnavailable
+	})
+	if !errors.Is(err, repository.ErrUnavailable) || calls != 1 {
+		t.Errorf("Do() = %v after %d calls, want to give up before exceeding the deadline", err, calls)
+	}
+}
+
+func TestRetrier_Do_nil(t *testing.T) {
+	var r *Retrier
+	calls := 0
+
+	_ = r.Do(context.Background(), func(_ context.Context) error {
+		calls++
+		return repository.ErrUnavailable
+	})
+	if calls != 1 {
+		t.Errorf("Do() calls = %d, want 1", calls)
+	}
+}
+
+func TestRetrier_delay(t *testing.T) {
+	r := NewRetrier(10, 100*time.Millisecond, time.Second)
+	r.jitter = func(n int64) int64 { return n - 1 }
+
+	for attempt, want := range map[int]time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		3: 400 * time.Millisecond,
+		4: 800 * time.Millisecond,
+		5: time.Second,
+		9: time.Second,
+	} {
+		if got := r.delay(attempt); got != want {
+			t.Errorf("delay(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}